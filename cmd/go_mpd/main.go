@@ -2,6 +2,9 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/arpitpandey992/go-mpd/internal/config"
 	"github.com/arpitpandey992/go-mpd/internal/database"
@@ -15,6 +18,12 @@ func main() {
 	}
 	audioMeilisearchClient := database.GetNewAudioMeiliSearchClient(config)
 	// database.SearchWithUserInput(audioMeilisearchClient)
-	server.CreateAndStartServer(audioMeilisearchClient)
-	select {}
+	mpdServer := server.CreateAndStartServer(config, audioMeilisearchClient)
+
+	// closing the server saves the player state, so it has to happen before exiting
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	receivedSignal := <-signals
+	log.Printf("received %v, shutting down", receivedSignal)
+	mpdServer.Close()
 }
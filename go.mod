@@ -2,7 +2,12 @@ module github.com/arpitpandey992/go-mpd
 
 go 1.21.3
 
-require github.com/gopxl/beep v1.1.0
+require (
+	github.com/gopxl/beep v1.1.0
+	github.com/meilisearch/meilisearch-go v0.27.2
+	github.com/mewkiz/flac v1.0.9
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/andybalholm/brotli v1.0.4 // indirect
@@ -13,8 +18,6 @@ require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/klauspost/compress v1.15.6 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
-	github.com/meilisearch/meilisearch-go v0.27.2 // indirect
-	github.com/mewkiz/flac v1.0.9 // indirect
 	github.com/mewkiz/pkg v0.0.0-20231012081350-95d6616c5403 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
@@ -23,5 +26,4 @@ require (
 	golang.org/x/image v0.13.0 // indirect
 	golang.org/x/mobile v0.0.0-20231006135142-2b44d11868fe // indirect
 	golang.org/x/sys v0.13.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,187 @@
+package audiofiles
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+// Finder expands file paths, directories and shell-style globs into the audio files they contain
+type Finder struct {
+	formats          map[string]bool                                   // lowercase extensions, including the dot
+	metadataResolver func(filePath string) *database.AudioFileMetadata // returns nil for unknown files, nil when there is no audio database
+}
+
+// File is an audio file found by the finder, along with its metadata from the audio database
+type File struct {
+	Path     string
+	Metadata *database.AudioFileMetadata // nil when the file is not in the audio database
+	Resolved bool                        // whether the metadata was looked up, so that it does not have to be looked up again
+}
+
+// FilePaths returns the paths of the files
+func FilePaths(files []File) []string {
+	filePaths := []string{}
+	for _, file := range files {
+		filePaths = append(filePaths, file.Path)
+	}
+	return filePaths
+}
+
+// NewFinder creates a finder for files with the given extensions, which may be written with or without the dot
+func NewFinder(formats []string) *Finder {
+	finder := &Finder{formats: map[string]bool{}}
+	for _, format := range formats {
+		finder.formats["."+strings.TrimPrefix(strings.ToLower(format), ".")] = true
+	}
+	return finder
+}
+
+// SetMetadataResolver sets the function used to look up the files in the audio database. Every file found is looked up once,
+// its disc and track numbers decide its order in a directory
+func (finder *Finder) SetMetadataResolver(metadataResolver func(filePath string) *database.AudioFileMetadata) {
+	finder.metadataResolver = metadataResolver
+}
+
+// IsPlainFile reports whether the argument names a single file or URL, which can be added without expanding it
+func IsPlainFile(argument string) bool {
+	if strings.Contains(argument, "://") {
+		return true
+	}
+	if isGlob(argument) {
+		return false
+	}
+	info, err := os.Stat(argument)
+	return err != nil || !info.IsDir()
+}
+
+// Expand returns the audio files for the arguments, in the order of the arguments. Directories are searched recursively
+// and their files are ordered by disc and track number where these are known, by their natural file name order otherwise.
+// URLs are passed through as they are. Arguments which lead to no audio file at all are returned as rejected
+func (finder *Finder) Expand(arguments []string) (files []File, rejected []string) {
+	files, rejected = []File{}, []string{}
+	for _, argument := range arguments {
+		found := finder.expandArgument(argument)
+		if len(found) == 0 {
+			rejected = append(rejected, argument)
+			continue
+		}
+		files = append(files, found...)
+	}
+	return files, rejected
+}
+
+func (finder *Finder) expandArgument(argument string) []File {
+	if strings.Contains(argument, "://") {
+		return []File{{Path: argument}}
+	}
+	if !isGlob(argument) {
+		return finder.expandPath(argument)
+	}
+	matches, err := filepath.Glob(argument)
+	if err != nil {
+		log.Printf("invalid glob pattern: %s, error: %v", argument, err)
+		return nil
+	}
+	sortNaturally(matches)
+	// like in a shell, wildcards do not match hidden files unless the pattern starts with a dot
+	matchHidden := strings.HasPrefix(filepath.Base(argument), ".")
+	found := []File{}
+	for _, match := range matches {
+		if !matchHidden && strings.HasPrefix(filepath.Base(match), ".") {
+			continue
+		}
+		found = append(found, finder.expandPath(match)...)
+	}
+	return found
+}
+
+func (finder *Finder) expandPath(filePath string) []File {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		log.Printf("could not find: %s, error: %v", filePath, err)
+		return nil
+	}
+	if info.IsDir() {
+		return finder.walkDirectory(filePath)
+	}
+	if !finder.isAudioFile(filePath) {
+		return nil
+	}
+	return []File{finder.newFile(filePath)}
+}
+
+func (finder *Finder) newFile(filePath string) File {
+	if finder.metadataResolver == nil {
+		return File{Path: filePath}
+	}
+	return File{Path: filePath, Metadata: finder.metadataResolver(filePath), Resolved: true}
+}
+
+// returns the audio files of a directory followed by the ones in its subdirectories, hidden files are skipped
+func (finder *Finder) walkDirectory(directory string) []File {
+	dirEntries, err := os.ReadDir(directory)
+	if err != nil {
+		log.Printf("could not read directory: %s, error: %v", directory, err)
+		return nil
+	}
+	files, subdirectories := []File{}, []string{}
+	for _, dirEntry := range dirEntries {
+		if strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		entryPath := filepath.Join(directory, dirEntry.Name())
+		if dirEntry.IsDir() {
+			subdirectories = append(subdirectories, entryPath)
+		} else if finder.isAudioFile(entryPath) {
+			files = append(files, finder.newFile(entryPath))
+		}
+	}
+	sortTracks(files)
+	sortNaturally(subdirectories)
+	for _, subdirectory := range subdirectories {
+		files = append(files, finder.walkDirectory(subdirectory)...)
+	}
+	return files
+}
+
+func (finder *Finder) isAudioFile(filePath string) bool {
+	return finder.formats[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// sorts the files of a directory by disc and track number, falling back to the natural order of their names
+func sortTracks(files []File) {
+	sort.SliceStable(files, func(i, j int) bool {
+		firstDisc, firstTrack := getTrackNumbers(files[i].Metadata)
+		secondDisc, secondTrack := getTrackNumbers(files[j].Metadata)
+		if firstDisc != secondDisc {
+			return firstDisc < secondDisc
+		}
+		if firstTrack != secondTrack {
+			return firstTrack < secondTrack
+		}
+		return naturalLess(files[i].Path, files[j].Path)
+	})
+}
+
+// returns 0 for unknown numbers
+func getTrackNumbers(metadata *database.AudioFileMetadata) (disc int, track int) {
+	if metadata == nil {
+		return 0, 0
+	}
+	if metadata.DiscNumber != nil {
+		disc = *metadata.DiscNumber
+	}
+	if metadata.TrackNumber != nil {
+		track = *metadata.TrackNumber
+	}
+	return disc, track
+}
+
+func isGlob(argument string) bool {
+	return strings.ContainsAny(argument, "*?[")
+}
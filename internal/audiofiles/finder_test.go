@@ -0,0 +1,83 @@
+package audiofiles
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+func TestNaturalLess(t *testing.T) {
+	names := []string{"10 - b.mp3", "2 - a.mp3", "Disc 10", "disc 9", "track.mp3", "track 01.mp3", "track 1.mp3"}
+	sortNaturally(names)
+	expected := []string{"2 - a.mp3", "10 - b.mp3", "disc 9", "Disc 10", "track 01.mp3", "track 1.mp3", "track.mp3"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected: %q, got: %q", expected, names)
+	}
+}
+
+func TestFinderExpand(t *testing.T) {
+	directory := t.TempDir()
+	for _, name := range []string{
+		"album/10 - b.mp3",
+		"album/2 - a.FLAC",
+		"album/cover.jpg",
+		"album/.hidden.mp3",
+		"album/CD 2/1 - c.mp3",
+		"empty/notes.txt",
+	} {
+		filePath := filepath.Join(directory, name)
+		checkError(os.MkdirAll(filepath.Dir(filePath), 0755), t)
+		checkError(os.WriteFile(filePath, nil, 0644), t)
+	}
+	finder := NewFinder([]string{"mp3", ".flac"})
+	join := func(name string) string { return filepath.Join(directory, name) }
+
+	files, rejected := finder.Expand([]string{
+		join("album"),
+		join("album/*.mp3"),
+		join("album/cover.jpg"),
+		join("empty"),
+		join("missing.mp3"),
+		"http://example.com/stream",
+	})
+	expectedFilePaths := []string{
+		join("album/2 - a.FLAC"), join("album/10 - b.mp3"), join("album/CD 2/1 - c.mp3"),
+		join("album/10 - b.mp3"),
+		"http://example.com/stream",
+	}
+	if filePaths := FilePaths(files); !reflect.DeepEqual(filePaths, expectedFilePaths) {
+		t.Errorf("expected: %q, got: %q", expectedFilePaths, filePaths)
+	}
+	expectedRejected := []string{join("album/cover.jpg"), join("empty"), join("missing.mp3")}
+	if !reflect.DeepEqual(rejected, expectedRejected) {
+		t.Errorf("expected rejected: %q, got: %q", expectedRejected, rejected)
+	}
+
+	lookups := map[string]int{}
+	finder.SetMetadataResolver(func(filePath string) *database.AudioFileMetadata {
+		lookups[filePath]++
+		disc, track := 1, 2
+		if filepath.Base(filePath) == "10 - b.mp3" {
+			track = 1
+		}
+		return &database.AudioFileMetadata{FilePath: filePath, DiscNumber: &disc, TrackNumber: &track}
+	})
+	files, _ = finder.Expand([]string{join("album")})
+	if files[0].Path != join("album/10 - b.mp3") {
+		t.Errorf("expected track numbers to decide the order, got: %q", FilePaths(files))
+	}
+	for _, file := range files {
+		if !file.Resolved || file.Metadata == nil || file.Metadata.FilePath != file.Path || lookups[file.Path] != 1 {
+			t.Errorf("expected the metadata of %s to be looked up once and kept, looked up %d times", file.Path, lookups[file.Path])
+		}
+	}
+}
+
+func checkError(err error, t *testing.T) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,49 @@
+package audiofiles
+
+import (
+	"sort"
+	"strings"
+)
+
+// sorts names so that embedded numbers are compared by their value, e.g. "2 - b.mp3" comes before "10 - a.mp3"
+func sortNaturally(names []string) {
+	sort.SliceStable(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+}
+
+func naturalLess(first, second string) bool {
+	first, second = strings.ToLower(first), strings.ToLower(second)
+	for first != "" && second != "" {
+		firstChunk, firstIsNumber := nextChunk(first)
+		secondChunk, secondIsNumber := nextChunk(second)
+		first, second = first[len(firstChunk):], second[len(secondChunk):]
+		if firstIsNumber && secondIsNumber {
+			// compare by value without parsing, so arbitrarily long numbers work: ignore leading zeros, then fewer digits is smaller
+			firstDigits, secondDigits := strings.TrimLeft(firstChunk, "0"), strings.TrimLeft(secondChunk, "0")
+			if len(firstDigits) != len(secondDigits) {
+				return len(firstDigits) < len(secondDigits)
+			}
+			if firstDigits != secondDigits {
+				return firstDigits < secondDigits
+			}
+			continue
+		}
+		if firstChunk != secondChunk {
+			return firstChunk < secondChunk
+		}
+	}
+	return len(first) < len(second)
+}
+
+// returns the leading run of digits or non digits of a non empty string, and whether it is a number
+func nextChunk(value string) (string, bool) {
+	isNumber := isDigit(value[0])
+	end := 1
+	for end < len(value) && isDigit(value[end]) == isNumber {
+		end++
+	}
+	return value[:end], isNumber
+}
+
+func isDigit(character byte) bool {
+	return character >= '0' && character <= '9'
+}
@@ -1,6 +1,7 @@
 package audioplayer
 
 import (
+	"fmt"
 	"log"
 	"time"
 
@@ -14,6 +15,8 @@ type AudioPlayer struct {
 	Streamer beep.StreamSeekCloser // Streamer is available to manipulate seek position
 	Format   beep.Format           // track metadata
 	Ctrl     *beep.Ctrl            // for play/pause functionality
+	Volume   *VolumeMixer          // gain of this track alone on top of Ctrl, used for replay gain
+	loop     *loopStreamer         // the same as Streamer, nil for audio players which were not decoded from a file
 }
 
 func (ap *AudioPlayer) Play() {
@@ -24,7 +27,9 @@ func (ap *AudioPlayer) Play() {
 	log.Print("debug: locked speaker")
 	speaker.Lock()
 	defer speaker.Unlock()
-	ap.Ctrl.Paused = false
+	if ap.Ctrl != nil {
+		ap.Ctrl.Paused = false
+	}
 	log.Print("debug: unlocked speaker")
 }
 
@@ -36,50 +41,98 @@ func (ap *AudioPlayer) Pause() {
 	log.Print("debug: locked speaker")
 	speaker.Lock()
 	defer speaker.Unlock()
-	ap.Ctrl.Paused = true
+	if ap.Ctrl != nil {
+		ap.Ctrl.Paused = true
+	}
 	log.Print("debug: unlocked speaker")
 }
 
 func (ap *AudioPlayer) GetCurrentPosition() time.Duration {
-	// TODO: check why Speaker.lock() is recommended here, this might result in race condition
+	// the speaker advances the position while streaming, so it has to be locked for reading it
+	speaker.Lock()
 	duration := ap.Format.SampleRate.D(ap.Streamer.Position())
-	return duration.Truncate(time.Second)
+	speaker.Unlock()
+	return duration.Truncate(time.Millisecond)
 }
 
+func (ap *AudioPlayer) GetDuration() time.Duration {
+	return ap.Format.SampleRate.D(ap.Streamer.Len())
+}
+
+// Seek moves to the given time with the accuracy of a single sample. Times outside of the track are rejected
 func (ap *AudioPlayer) Seek(seekTime time.Duration) error {
-	// Seeks to the given time (granular upto seconds of accuracy)
+	speaker.Lock()
+	defer speaker.Unlock()
+	return ap.seek(seekTime)
+}
+
+// same as Seek, but expects the speaker to be locked already
+func (ap *AudioPlayer) seek(seekTime time.Duration) error {
+	sample := ap.Format.SampleRate.N(seekTime)
+	if seekTime < 0 || sample > ap.Streamer.Len() {
+		return fmt.Errorf("seek position %v is outside of the track (0s-%v)", seekTime, ap.GetDuration())
+	}
 	log.Printf("seeking to %v", seekTime)
-	samplesToSeek := int64(ap.Format.SampleRate) * int64(seekTime.Seconds())
+	err := ap.Streamer.Seek(sample)
+	if err != nil {
+		log.Printf("error while seeking: %v", err)
+	}
+	return err
+}
 
-	returnValue := ap.runWithAudioPlayerLock(func() interface{} { return ap.Streamer.Seek(int(samplesToSeek)) })
-	if err, ok := returnValue.(error); ok {
-		if err != nil {
-			log.Printf("error while seeking: %v", err)
-		}
-		return err
+// SetLoop plays the part of the track between a and b over and over, with the accuracy of a single sample.
+// The track does not finish while it loops, playback continues from where it is until it reaches b
+func (ap *AudioPlayer) SetLoop(a, b time.Duration) error {
+	if ap.loop == nil {
+		return fmt.Errorf("the audio player can not loop")
+	}
+	if a < 0 || a >= b || b > ap.GetDuration() {
+		return fmt.Errorf("invalid loop %v-%v, expected A before B within the track (0s-%v)", a, b, ap.GetDuration())
 	}
-	return nil
+	speaker.Lock()
+	defer speaker.Unlock()
+	return ap.loop.setLoop(ap.Format.SampleRate.N(a), ap.Format.SampleRate.N(b))
 }
 
+// ClearLoop lets the track play on until its end
+func (ap *AudioPlayer) ClearLoop() {
+	if ap.loop == nil {
+		return
+	}
+	speaker.Lock()
+	defer speaker.Unlock()
+	ap.loop.clearLoop()
+}
+
+// GetLoop returns the loop points, looping is false when no loop is set
+func (ap *AudioPlayer) GetLoop() (a, b time.Duration, looping bool) {
+	speaker.Lock()
+	defer speaker.Unlock()
+	if !ap.isLooping() {
+		return 0, 0, false
+	}
+	return ap.Format.SampleRate.D(ap.loop.a), ap.Format.SampleRate.D(ap.loop.b), true
+}
+
+// same as GetLoop without the loop points, but expects the speaker to be locked already
+func (ap *AudioPlayer) isLooping() bool {
+	return ap.loop != nil && ap.loop.looping
+}
+
+// IsPaused reports whether the audio player is paused, a closed one always is
 func (ap *AudioPlayer) IsPaused() bool {
-	return ap.Ctrl.Paused
+	return ap.Ctrl == nil || ap.Ctrl.Paused
 }
 
+// Close releases the decoder, closing an audio player again does nothing
 func (ap *AudioPlayer) Close() error {
-	if !ap.IsPaused() {
-		ap.Pause()
+	speaker.Lock()
+	if ap.Ctrl == nil {
+		speaker.Unlock()
+		return nil
 	}
+	ap.Ctrl.Paused = true
 	ap.Ctrl = nil
+	speaker.Unlock()
 	return ap.Streamer.Close()
 }
-func (ap *AudioPlayer) runWithAudioPlayerLock(callable func() interface{}) any {
-	initiallyPlaying := !ap.IsPaused()
-	if initiallyPlaying {
-		ap.Pause()
-	}
-	returnValue := callable()
-	if initiallyPlaying {
-		ap.Play()
-	}
-	return returnValue
-}
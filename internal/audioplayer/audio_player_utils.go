@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/arpitpandey992/go-mpd/internal/utils"
 	"github.com/gopxl/beep"
@@ -20,6 +21,9 @@ func logError(err error) error {
 	return err
 }
 
+// SupportedExtensions are the file extensions of the audio formats which can be decoded
+var SupportedExtensions = []string{".mp3", ".flac"}
+
 func IsFileSupported(filePath string) error {
 	fileExists, err := utils.DoesFileExistsInFileSystem(filePath)
 	if err != nil {
@@ -28,9 +32,8 @@ func IsFileSupported(filePath string) error {
 	if !fileExists {
 		return fmt.Errorf("file does not exist at: %s", filePath)
 	}
-	supportedExtensions := []string{".mp3", ".flac"}
 	fileExtension := strings.ToLower(filepath.Ext(filePath))
-	for _, extension := range supportedExtensions {
+	for _, extension := range SupportedExtensions {
 		if extension == fileExtension {
 			return nil
 		}
@@ -39,6 +42,12 @@ func IsFileSupported(filePath string) error {
 }
 
 func CreateAudioPlayer(filePath string, callbackFunction func()) (*AudioPlayer, error) {
+	return CreateAudioPlayerForRange(filePath, 0, 0, callbackFunction)
+}
+
+// CreateAudioPlayerForRange creates an audio player which only plays the part of the file between start and end,
+// an end of 0 plays until the end of the file
+func CreateAudioPlayerForRange(filePath string, start, end time.Duration, callbackFunction func()) (*AudioPlayer, error) {
 	err := IsFileSupported(filePath)
 	if logError(err) != nil {
 		return nil, err
@@ -47,14 +56,14 @@ func CreateAudioPlayer(filePath string, callbackFunction func()) (*AudioPlayer,
 	if logError(err) != nil {
 		return nil, err
 	}
-	audioPlayer, err := getNewAudioPlayer(audioFile, callbackFunction)
+	audioPlayer, err := getNewAudioPlayer(audioFile, start, end, callbackFunction)
 	if logError(err) != nil {
 		return nil, err
 	}
 	return audioPlayer, nil
 }
 
-func getNewAudioPlayer(file *os.File, callbackfunc func()) (*AudioPlayer, error) {
+func getNewAudioPlayer(file *os.File, start, end time.Duration, callbackfunc func()) (*AudioPlayer, error) {
 	fileFormat := strings.ToLower(filepath.Ext(file.Name()))
 	var DecoderMap = map[string]func(*os.File) (s beep.StreamSeekCloser, format beep.Format, err error){
 		".mp3":  func(file *os.File) (s beep.StreamSeekCloser, format beep.Format, err error) { return mp3.Decode(file) },
@@ -64,7 +73,17 @@ func getNewAudioPlayer(file *os.File, callbackfunc func()) (*AudioPlayer, error)
 	if logError(err) != nil {
 		return nil, err
 	}
+	if start > 0 || end > 0 {
+		rangedStreamer, err := newRangeStreamer(streamer, format.SampleRate.N(start), format.SampleRate.N(end))
+		if logError(err) != nil {
+			streamer.Close()
+			return nil, err
+		}
+		streamer = rangedStreamer
+	}
 
-	ctrl := &beep.Ctrl{Streamer: beep.Seq(streamer, beep.Callback(callbackfunc)), Paused: true}
-	return &AudioPlayer{Ctrl: ctrl, Streamer: streamer, Format: format}, nil
+	loop := newLoopStreamer(streamer)
+	ctrl := &beep.Ctrl{Streamer: beep.Seq(loop, beep.Callback(callbackfunc)), Paused: true}
+	volume := NewVolumeMixer(ctrl, format.SampleRate)
+	return &AudioPlayer{Ctrl: ctrl, Volume: volume, Streamer: loop, Format: format, loop: loop}, nil
 }
@@ -0,0 +1,183 @@
+package audioplayer
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/speaker"
+)
+
+// GaplessSequencer streams audio players back to back. The next audio player is resampled and queued
+// ahead of time, so its first sample directly follows the last sample of the current one, or so that both
+// are mixed with fade curves when a crossfade is requested.
+// It never drains and streams silence while there is nothing to play, so it is added to the speaker only once
+type GaplessSequencer struct {
+	sampleRate beep.SampleRate // sample rate of the speaker, every audio player is resampled to it
+
+	current         *AudioPlayer
+	currentStreamer beep.Streamer
+	next            *AudioPlayer
+	nextStreamer    beep.Streamer
+
+	nextPromoted bool // whether the audio player last queued with SetNext has become the current one
+
+	crossfade    int // length of the crossfade into the next audio player in samples, 0 for a gapless transition
+	fadeLength   int // length of the crossfade in progress, 0 while not fading
+	fadePosition int
+	mixBuffer    [][2]float64
+}
+
+func NewGaplessSequencer(sampleRate beep.SampleRate) *GaplessSequencer {
+	return &GaplessSequencer{sampleRate: sampleRate}
+}
+
+// SetCurrent replaces the audio player which is being streamed right now, nil streams silence
+func (gs *GaplessSequencer) SetCurrent(ap *AudioPlayer) {
+	streamer := gs.resampleIfNeeded(ap)
+	speaker.Lock()
+	defer speaker.Unlock()
+	gs.current, gs.currentStreamer = ap, streamer
+	gs.nextPromoted = false
+	gs.fadeLength = 0
+}
+
+// SetNext queues the audio player to switch to once the current one is drained, nil removes it.
+// With a non zero crossfade, the next audio player starts that long before the current one ends and both are mixed.
+// It reports whether the previously queued audio player was switched to already, it is streamed as the current one then
+// and must not be closed
+func (gs *GaplessSequencer) SetNext(ap *AudioPlayer, crossfade time.Duration) (promoted bool) {
+	streamer := gs.resampleIfNeeded(ap)
+	speaker.Lock()
+	defer speaker.Unlock()
+	promoted = gs.nextPromoted
+	gs.next, gs.nextStreamer = ap, streamer
+	gs.nextPromoted = false
+	gs.crossfade = gs.sampleRate.N(crossfade)
+	gs.fadeLength = 0
+	return promoted
+}
+
+// SetCrossfade changes the crossfade into the next audio player, unless the crossfade has begun already
+func (gs *GaplessSequencer) SetCrossfade(crossfade time.Duration) {
+	speaker.Lock()
+	defer speaker.Unlock()
+	if gs.fadeLength == 0 {
+		gs.crossfade = gs.sampleRate.N(crossfade)
+	}
+}
+
+// SeekCurrent seeks the current audio player and restarts its resampling, so no samples buffered from before the seek
+// are streamed afterwards. A crossfade in progress is cancelled, the next audio player should be queued again
+func (gs *GaplessSequencer) SeekCurrent(seekTime time.Duration) error {
+	speaker.Lock()
+	defer speaker.Unlock()
+	if gs.current == nil {
+		return fmt.Errorf("no audio player to seek")
+	}
+	err := gs.current.seek(seekTime)
+	if err != nil {
+		return err
+	}
+	gs.currentStreamer = gs.resampleIfNeeded(gs.current)
+	gs.fadeLength = 0
+	return nil
+}
+
+// Clear removes both the current and the next audio player. They are not touched by the speaker afterwards and can be closed
+func (gs *GaplessSequencer) Clear() {
+	speaker.Lock()
+	defer speaker.Unlock()
+	gs.current, gs.currentStreamer = nil, nil
+	gs.next, gs.nextStreamer = nil, nil
+	gs.nextPromoted = false
+	gs.fadeLength = 0
+}
+
+func (gs *GaplessSequencer) Stream(samples [][2]float64) (n int, ok bool) {
+	// a paused audio player does not advance, so neither should a crossfade into the next one
+	for n < len(samples) && gs.currentStreamer != nil && !gs.current.IsPaused() {
+		chunk := samples[n:]
+		// a looping audio player does not end, so there is nothing to crossfade from
+		if gs.fadeLength == 0 && gs.nextStreamer != nil && gs.crossfade > 0 && !gs.current.isLooping() {
+			remaining := gs.getRemainingSamples()
+			if remaining <= gs.crossfade {
+				gs.fadeLength, gs.fadePosition = max(remaining, 1), 0
+			} else if len(chunk) > remaining-gs.crossfade {
+				// stop right where the crossfade has to begin
+				chunk = chunk[:remaining-gs.crossfade]
+			}
+		}
+		var sn int
+		var drained bool
+		if gs.fadeLength > 0 {
+			sn, drained = gs.streamCrossfade(chunk)
+		} else {
+			var sok bool
+			sn, sok = gs.currentStreamer.Stream(chunk)
+			// streamers only return fewer samples than requested once they are drained
+			drained = !sok || sn < len(chunk)
+		}
+		n += sn
+		if drained {
+			gs.nextPromoted = gs.next != nil
+			gs.current, gs.currentStreamer = gs.next, gs.nextStreamer
+			gs.next, gs.nextStreamer = nil, nil
+			gs.fadeLength = 0
+		}
+	}
+	for i := n; i < len(samples); i++ {
+		samples[i] = [2]float64{}
+	}
+	return len(samples), true
+}
+
+func (gs *GaplessSequencer) Err() error {
+	return nil
+}
+
+// mixes the current and the next streamer into samples with equal power fade curves. Once the current
+// streamer is drained, the rest of samples is filled by the next one alone
+func (gs *GaplessSequencer) streamCrossfade(samples [][2]float64) (n int, drained bool) {
+	if cap(gs.mixBuffer) < len(samples) {
+		gs.mixBuffer = make([][2]float64, len(samples))
+	}
+	nextSamples := gs.mixBuffer[:len(samples)]
+	nn, _ := gs.nextStreamer.Stream(nextSamples)
+	for i := nn; i < len(nextSamples); i++ {
+		nextSamples[i] = [2]float64{}
+	}
+	cn, cok := gs.currentStreamer.Stream(samples)
+	for i := range samples {
+		if i >= cn {
+			samples[i] = nextSamples[i]
+			continue
+		}
+		progress := min(float64(gs.fadePosition)/float64(gs.fadeLength), 1)
+		fadeOut, fadeIn := math.Cos(progress*math.Pi/2), math.Sin(progress*math.Pi/2)
+		for c := range samples[i] {
+			samples[i][c] = samples[i][c]*fadeOut + nextSamples[i][c]*fadeIn
+		}
+		gs.fadePosition++
+	}
+	return len(samples), !cok || cn < len(samples)
+}
+
+// returns how many samples, at the sample rate of the sequencer, are left in the current audio player
+func (gs *GaplessSequencer) getRemainingSamples() int {
+	remaining := gs.current.Streamer.Len() - gs.current.Streamer.Position()
+	return gs.sampleRate.N(gs.current.Format.SampleRate.D(remaining))
+}
+
+func (gs *GaplessSequencer) resampleIfNeeded(ap *AudioPlayer) beep.Streamer {
+	if ap == nil {
+		return nil
+	}
+	if ap.Format.SampleRate == gs.sampleRate {
+		return ap.Volume
+	}
+	log.Printf("resampling from %d to %d", ap.Format.SampleRate, gs.sampleRate)
+	return beep.Resample(4, ap.Format.SampleRate, gs.sampleRate, ap.Volume)
+}
@@ -0,0 +1,153 @@
+package audioplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep"
+)
+
+// constantStreamer streams a fixed number of samples which all have the same value
+type constantStreamer struct {
+	value    float64
+	position int
+	length   int
+}
+
+func (cs *constantStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	if cs.position >= cs.length {
+		return 0, false
+	}
+	n = min(len(samples), cs.length-cs.position)
+	for i := range samples[:n] {
+		samples[i] = [2]float64{cs.value, cs.value}
+	}
+	cs.position += n
+	return n, true
+}
+
+func (cs *constantStreamer) Err() error       { return nil }
+func (cs *constantStreamer) Len() int         { return cs.length }
+func (cs *constantStreamer) Position() int    { return cs.position }
+func (cs *constantStreamer) Seek(p int) error { cs.position = p; return nil }
+func (cs *constantStreamer) Close() error     { return nil }
+
+// creates an unpaused audio player streaming numberOfSamples samples of the given value
+func getConstantAudioPlayer(value float64, numberOfSamples int, callbackFunction func()) *AudioPlayer {
+	streamer := &constantStreamer{value: value, length: numberOfSamples}
+	ctrl := &beep.Ctrl{Streamer: beep.Seq(streamer, beep.Callback(callbackFunction))}
+	format := beep.Format{SampleRate: 44100, NumChannels: 2, Precision: 2}
+	return &AudioPlayer{Streamer: streamer, Format: format, Ctrl: ctrl, Volume: NewVolumeMixer(ctrl, format.SampleRate)}
+}
+
+func TestGaplessSequencer(t *testing.T) {
+	finished := 0
+	sequencer := NewGaplessSequencer(44100)
+	sequencer.SetCurrent(getConstantAudioPlayer(1, 100, func() { finished++ }))
+	sequencer.SetNext(getConstantAudioPlayer(0.5, 50, func() { finished++ }), 0)
+
+	samples := make([][2]float64, 64)
+	streamed := [][2]float64{}
+	for i := 0; i < 3; i++ {
+		n, ok := sequencer.Stream(samples)
+		if n != len(samples) || !ok {
+			t.Fatalf("the sequencer should never drain, got: (%d, %v)", n, ok)
+		}
+		streamed = append(streamed, samples...)
+	}
+
+	for i, sample := range streamed {
+		expected := 0.0
+		switch {
+		case i < 100:
+			expected = 1
+		case i < 150:
+			expected = 0.5
+		}
+		if sample[0] != expected {
+			t.Fatalf("sample %d: expected %v, got %v", i, expected, sample[0])
+		}
+	}
+	if finished != 2 {
+		t.Errorf("expected both audio players to finish, finished: %d", finished)
+	}
+}
+
+func TestGaplessSequencerCrossfade(t *testing.T) {
+	finished := 0
+	sequencer := NewGaplessSequencer(44100)
+	sequencer.SetCurrent(getConstantAudioPlayer(1, 100, func() { finished++ }))
+	sequencer.SetNext(getConstantAudioPlayer(0.5, 100, func() { finished++ }), beep.SampleRate(44100).D(20))
+
+	streamed := make([][2]float64, 256)
+	sequencer.Stream(streamed)
+
+	// the last 20 samples of the current track overlap with the first ones of the next track
+	if streamed[79][0] != 1 {
+		t.Errorf("the crossfade started too early, sample 79: %v", streamed[79][0])
+	}
+	if mixed := streamed[90][0]; mixed <= 0.5 || mixed >= 1.5 {
+		t.Errorf("expected both tracks mixed at sample 90, got: %v", mixed)
+	}
+	if streamed[150][0] != 0.5 || streamed[185][0] != 0 {
+		t.Errorf("expected the next track to end at sample 180, got: %v, %v", streamed[150][0], streamed[185][0])
+	}
+	if finished != 2 {
+		t.Errorf("expected both audio players to finish, finished: %d", finished)
+	}
+}
+
+func TestGaplessSequencerSeek(t *testing.T) {
+	// the audio player runs at half the rate of the sequencer, so it is resampled
+	ap := getConstantAudioPlayer(1, 22050, func() {})
+	ap.Format.SampleRate = 22050
+	sequencer := NewGaplessSequencer(44100)
+	sequencer.SetCurrent(ap)
+	sequencer.Stream(make([][2]float64, 1000))
+
+	if err := sequencer.SeekCurrent(time.Millisecond * 250); err != nil {
+		t.Fatal(err)
+	}
+	if position := ap.Streamer.Position(); position != 5512 {
+		t.Errorf("expected a seek to sample 5512, got: %d", position)
+	}
+	// the resampler buffers ahead, streaming after the seek has to continue right from the new position
+	sequencer.Stream(make([][2]float64, 1))
+	if position := ap.Streamer.Position(); position != 5512+512 {
+		t.Errorf("expected the resampler to restart from the new position, got: %d", position)
+	}
+	if err := sequencer.SeekCurrent(time.Second * 2); err == nil {
+		t.Error("expected an error for seeking beyond the end of the track")
+	}
+}
+
+func TestGaplessSequencerPromotedNext(t *testing.T) {
+	sequencer := NewGaplessSequencer(44100)
+	sequencer.SetCurrent(getConstantAudioPlayer(1, 100, func() {}))
+	next := getConstantAudioPlayer(0.5, 100, func() {})
+	sequencer.SetNext(next, 0)
+	if promoted := sequencer.SetNext(next, 0); promoted {
+		t.Error("expected the next audio player not to be switched to before the current one is drained")
+	}
+
+	// the current audio player drains before its finish callback can catch up, removing the next one is too late then
+	sequencer.Stream(make([][2]float64, 150))
+	if promoted := sequencer.SetNext(nil, 0); !promoted {
+		t.Fatal("expected the next audio player to be reported as switched to")
+	}
+	if promoted := sequencer.SetNext(nil, 0); promoted {
+		t.Error("expected the switch to be reported only once")
+	}
+	samples := make([][2]float64, 10)
+	sequencer.Stream(samples)
+	if samples[0][0] != 0.5 {
+		t.Errorf("expected the switched to audio player to keep streaming, got: %v", samples[0][0])
+	}
+
+	// a closed audio player is never streamed, instead of crashing the speaker
+	next.Close()
+	sequencer.Stream(samples)
+	if samples[0][0] != 0 {
+		t.Errorf("expected silence for a closed audio player, got: %v", samples[0][0])
+	}
+}
@@ -0,0 +1,53 @@
+package audioplayer
+
+import (
+	"fmt"
+
+	"github.com/gopxl/beep"
+)
+
+// loopStreamer jumps back from sample b to sample a of the wrapped streamer for as long as a loop is set.
+// It never drains while looping, so the callback following it is not called until the loop is cleared
+type loopStreamer struct {
+	beep.StreamSeekCloser
+	a, b    int
+	looping bool
+}
+
+func newLoopStreamer(streamer beep.StreamSeekCloser) *loopStreamer {
+	return &loopStreamer{StreamSeekCloser: streamer}
+}
+
+func (ls *loopStreamer) setLoop(a, b int) error {
+	if a < 0 || a >= b || b > ls.Len() {
+		return fmt.Errorf("invalid loop of samples %d-%d, the track has %d", a, b, ls.Len())
+	}
+	ls.a, ls.b, ls.looping = a, b, true
+	return nil
+}
+
+func (ls *loopStreamer) clearLoop() {
+	ls.a, ls.b, ls.looping = 0, 0, false
+}
+
+func (ls *loopStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	if !ls.looping {
+		return ls.StreamSeekCloser.Stream(samples)
+	}
+	for n < len(samples) {
+		// positions past b, like after seeking there, jump back as well
+		if ls.Position() >= ls.b {
+			if err := ls.StreamSeekCloser.Seek(ls.a); err != nil {
+				return n, n > 0
+			}
+		}
+		chunk := samples[n:min(len(samples), n+ls.b-ls.Position())]
+		sn, sok := ls.StreamSeekCloser.Stream(chunk)
+		n += sn
+		if !sok || sn < len(chunk) {
+			// the decoder ended before b, the loop can not continue
+			return n, n > 0
+		}
+	}
+	return n, true
+}
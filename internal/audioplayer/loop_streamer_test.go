@@ -0,0 +1,39 @@
+package audioplayer
+
+import "testing"
+
+func TestLoopStreamer(t *testing.T) {
+	streamer := newLoopStreamer(&constantStreamer{value: 1, length: 1000})
+	if err := streamer.setLoop(300, 100); err == nil {
+		t.Error("expected an error for B before A")
+	}
+	if err := streamer.setLoop(100, 1001); err == nil {
+		t.Error("expected an error for B beyond the end of the track")
+	}
+	if err := streamer.setLoop(100, 300); err != nil {
+		t.Fatal(err)
+	}
+	_ = streamer.Seek(250)
+	samples := make([][2]float64, 100)
+	if n, ok := streamer.Stream(samples); n != 100 || !ok || streamer.Position() != 150 {
+		t.Errorf("expected to jump back to A after 50 samples, streamed %d, now at %d", n, streamer.Position())
+	}
+
+	// a loop at the very end of the track never drains
+	if err := streamer.setLoop(900, 1000); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if n, ok := streamer.Stream(samples); n != 100 || !ok {
+			t.Fatalf("expected the loop to keep streaming, streamed %d at %d", n, streamer.Position())
+		}
+	}
+	streamer.clearLoop()
+	_ = streamer.Seek(950)
+	if n, _ := streamer.Stream(samples); n != 50 {
+		t.Errorf("expected the track to end once the loop is cleared, streamed: %d", n)
+	}
+	if _, ok := streamer.Stream(samples); ok {
+		t.Error("expected the track to be drained")
+	}
+}
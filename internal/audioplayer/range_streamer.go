@@ -0,0 +1,55 @@
+package audioplayer
+
+import (
+	"fmt"
+
+	"github.com/gopxl/beep"
+)
+
+// rangeStreamer plays only the samples in [start, end) of the wrapped streamer, like a single track of a cue sheet.
+// Positions, the length and seeks are relative to start, so the range looks like a track of its own
+type rangeStreamer struct {
+	beep.StreamSeekCloser
+	start int
+	end   int
+}
+
+func newRangeStreamer(streamer beep.StreamSeekCloser, start, end int) (*rangeStreamer, error) {
+	if end <= 0 {
+		end = streamer.Len()
+	}
+	if start < 0 || start >= end || end > streamer.Len() {
+		return nil, fmt.Errorf("invalid range of samples %d-%d, the track has %d", start, end, streamer.Len())
+	}
+	err := streamer.Seek(start)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeStreamer{StreamSeekCloser: streamer, start: start, end: end}, nil
+}
+
+func (rs *rangeStreamer) Stream(samples [][2]float64) (n int, ok bool) {
+	remaining := rs.end - rs.StreamSeekCloser.Position()
+	if remaining <= 0 {
+		return 0, false
+	}
+	if len(samples) > remaining {
+		samples = samples[:remaining]
+	}
+	return rs.StreamSeekCloser.Stream(samples)
+}
+
+func (rs *rangeStreamer) Len() int {
+	return rs.end - rs.start
+}
+
+func (rs *rangeStreamer) Position() int {
+	return rs.StreamSeekCloser.Position() - rs.start
+}
+
+func (rs *rangeStreamer) Seek(p int) error {
+	if p < 0 || p > rs.Len() {
+		return fmt.Errorf("seek position %d is outside of the range of %d samples", p, rs.Len())
+	}
+	return rs.StreamSeekCloser.Seek(rs.start + p)
+}
@@ -0,0 +1,29 @@
+package audioplayer
+
+import "testing"
+
+func TestRangeStreamer(t *testing.T) {
+	streamer, err := newRangeStreamer(&constantStreamer{value: 1, length: 1000}, 200, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamer.Len() != 300 || streamer.Position() != 0 {
+		t.Errorf("expected a 300 samples long range at position 0, got: %d at %d", streamer.Len(), streamer.Position())
+	}
+	samples := make([][2]float64, 512)
+	if n, _ := streamer.Stream(samples); n != 300 {
+		t.Errorf("expected the range to drain after 300 samples, streamed: %d", n)
+	}
+	if _, ok := streamer.Stream(samples); ok {
+		t.Error("expected the range to stay drained")
+	}
+	if err := streamer.Seek(100); err != nil || streamer.Position() != 100 {
+		t.Errorf("expected a seek relative to the start of the range, got position %d, error: %v", streamer.Position(), err)
+	}
+	if err := streamer.Seek(301); err == nil {
+		t.Error("expected an error for seeking beyond the range")
+	}
+	if _, err := newRangeStreamer(&constantStreamer{value: 1, length: 1000}, 900, 1200); err == nil {
+		t.Error("expected an error for a range beyond the end of the track")
+	}
+}
@@ -0,0 +1,73 @@
+package audioplayer
+
+import (
+	"time"
+
+	"github.com/gopxl/beep"
+	"github.com/gopxl/beep/speaker"
+)
+
+const volumeRampDuration = time.Millisecond * 50 // gain changes are spread over this long to avoid clicks
+
+// VolumeMixer scales the samples of the wrapped streamer by a gain.
+// Changes to the gain are ramped linearly instead of being applied at once
+type VolumeMixer struct {
+	Streamer beep.Streamer
+
+	gain       float64
+	targetGain float64
+	rampStep   float64 // maximum change of the gain per sample
+	closed     bool    // makes the speaker drop the mixer
+}
+
+func NewVolumeMixer(streamer beep.Streamer, sampleRate beep.SampleRate) *VolumeMixer {
+	return &VolumeMixer{
+		Streamer:   streamer,
+		gain:       1,
+		targetGain: 1,
+		rampStep:   1 / float64(max(sampleRate.N(volumeRampDuration), 1)),
+	}
+}
+
+// SetGain ramps the gain to the given value, gains above 1 amplify the samples
+func (vm *VolumeMixer) SetGain(gain float64) {
+	speaker.Lock()
+	defer speaker.Unlock()
+	vm.targetGain = max(gain, 0)
+}
+
+// ResetGain applies the gain at once, meant for streamers which are not being played yet
+func (vm *VolumeMixer) ResetGain(gain float64) {
+	speaker.Lock()
+	defer speaker.Unlock()
+	vm.gain, vm.targetGain = max(gain, 0), max(gain, 0)
+}
+
+// Close ends the stream, the speaker stops pulling samples from the mixer and drops it
+func (vm *VolumeMixer) Close() {
+	speaker.Lock()
+	defer speaker.Unlock()
+	vm.closed = true
+}
+
+func (vm *VolumeMixer) Stream(samples [][2]float64) (n int, ok bool) {
+	if vm.closed {
+		return 0, false
+	}
+	n, ok = vm.Streamer.Stream(samples)
+	for i := range samples[:n] {
+		switch {
+		case vm.gain < vm.targetGain:
+			vm.gain = min(vm.gain+vm.rampStep, vm.targetGain)
+		case vm.gain > vm.targetGain:
+			vm.gain = max(vm.gain-vm.rampStep, vm.targetGain)
+		}
+		samples[i][0] *= vm.gain
+		samples[i][1] *= vm.gain
+	}
+	return n, ok
+}
+
+func (vm *VolumeMixer) Err() error {
+	return vm.Streamer.Err()
+}
@@ -0,0 +1,30 @@
+package audioplayer
+
+import "testing"
+
+func TestVolumeMixerRamping(t *testing.T) {
+	mixer := NewVolumeMixer(&constantStreamer{value: 1, length: 10000}, 44100)
+	mixer.SetGain(0)
+
+	samples := make([][2]float64, 4410)
+	mixer.Stream(samples)
+	if samples[0][0] < 0.99 {
+		t.Errorf("the gain should ramp down instead of jumping, first sample: %v", samples[0][0])
+	}
+	for i := 1; i < len(samples); i++ {
+		if samples[i][0] > samples[i-1][0] {
+			t.Fatalf("the gain increased at sample %d while ramping down", i)
+		}
+	}
+	if last := samples[len(samples)-1][0]; last != 0 {
+		t.Errorf("expected silence once the ramp is over, got: %v", last)
+	}
+}
+
+func TestVolumeMixerClose(t *testing.T) {
+	mixer := NewVolumeMixer(&constantStreamer{value: 1, length: 10000}, 44100)
+	mixer.Close()
+	if n, ok := mixer.Stream(make([][2]float64, 512)); n != 0 || ok {
+		t.Errorf("expected a closed mixer to end the stream, got: %d, %v", n, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package autodj
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+// Strategy decides what the chosen tracks have in common with the recently played ones
+type Strategy string
+
+const (
+	StrategyArtist      Strategy = "artist"
+	StrategyAlbumArtist Strategy = "album_artist"
+	StrategyGenre       Strategy = "genre"
+	StrategyDate        Strategy = "date" // released within DateRange years of a recently played track
+)
+
+var AllStrategies = []Strategy{StrategyArtist, StrategyAlbumArtist, StrategyGenre, StrategyDate}
+
+const searchLimit = 50 // results looked at per search
+
+func ParseStrategy(name string) (Strategy, error) {
+	for _, strategy := range AllStrategies {
+		if string(strategy) == strings.ToLower(name) {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized auto-DJ strategy: %s", name)
+}
+
+// Settings decide which tracks are chosen
+type Settings struct {
+	Strategy  Strategy
+	SeedQuery string // searched when nothing fits the recently played tracks, an empty query matches the whole library
+	DateRange int    // years before and after a recently played track, for StrategyDate
+}
+
+// Selector picks tracks from the library which fit the recently played ones
+type Selector struct {
+	search   func(query string, limit int64) ([]database.AudioFileMetadata, error)
+	settings Settings
+}
+
+// NewSelector creates a selector searching the library with search, which is usually AudioMeilisearchClient.SearchAudioFiles
+func NewSelector(search func(query string, limit int64) ([]database.AudioFileMetadata, error), settings Settings) *Selector {
+	return &Selector{search: search, settings: settings}
+}
+
+// Choose returns up to count file paths of tracks fitting the seeds, which are the recently played tracks, most recent first.
+// Excluded file paths are never chosen. When not enough tracks fit the seeds, the rest are found with the seed query
+func (selector *Selector) Choose(seeds []database.AudioFileMetadata, excluded map[string]bool, count int) ([]string, error) {
+	chosen := map[string]bool{}
+	filePaths := []string{}
+	pick := func(candidates []database.AudioFileMetadata) {
+		rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+		for _, candidate := range candidates {
+			filePath := filepath.Clean(candidate.FilePath)
+			if len(filePaths) < count && !excluded[filePath] && !chosen[filePath] {
+				chosen[filePath] = true
+				filePaths = append(filePaths, filePath)
+			}
+		}
+	}
+	for _, seed := range seeds {
+		if len(filePaths) >= count {
+			break
+		}
+		candidates := []database.AudioFileMetadata{}
+		for _, query := range selector.queries(seed) {
+			results, err := selector.search(query, searchLimit)
+			if err != nil {
+				return filePaths, fmt.Errorf("error searching for %q: %w", query, err)
+			}
+			for _, result := range results {
+				if selector.fits(seed, result) {
+					candidates = append(candidates, result)
+				}
+			}
+		}
+		pick(candidates)
+	}
+	if len(filePaths) < count {
+		results, err := selector.search(selector.settings.SeedQuery, searchLimit)
+		if err != nil {
+			return filePaths, fmt.Errorf("error searching for the seed query %q: %w", selector.settings.SeedQuery, err)
+		}
+		pick(results)
+	}
+	log.Printf("auto-DJ chose %d of %d tracks", len(filePaths), count)
+	return filePaths, nil
+}
+
+// returns the searches finding the tracks which may fit the seed, the results still have to be checked with fits
+func (selector *Selector) queries(seed database.AudioFileMetadata) []string {
+	switch selector.settings.Strategy {
+	case StrategyAlbumArtist:
+		return seed.AlbumArtist
+	case StrategyGenre:
+		if seed.Genre == nil {
+			return nil
+		}
+		return []string{*seed.Genre}
+	case StrategyDate:
+		year, found := getYear(seed.Date)
+		if !found {
+			return nil
+		}
+		queries := []string{}
+		for offset := -selector.settings.DateRange; offset <= selector.settings.DateRange; offset++ {
+			queries = append(queries, strconv.Itoa(year+offset))
+		}
+		return queries
+	default:
+		return seed.Artist
+	}
+}
+
+// search results match any field, so they are checked for having the field of the strategy in common with the seed
+func (selector *Selector) fits(seed, candidate database.AudioFileMetadata) bool {
+	switch selector.settings.Strategy {
+	case StrategyAlbumArtist:
+		return haveCommonValue(seed.AlbumArtist, candidate.AlbumArtist)
+	case StrategyGenre:
+		return seed.Genre != nil && candidate.Genre != nil && strings.EqualFold(*seed.Genre, *candidate.Genre)
+	case StrategyDate:
+		seedYear, seedFound := getYear(seed.Date)
+		candidateYear, candidateFound := getYear(candidate.Date)
+		return seedFound && candidateFound && max(seedYear-candidateYear, candidateYear-seedYear) <= selector.settings.DateRange
+	default:
+		return haveCommonValue(seed.Artist, candidate.Artist)
+	}
+}
+
+func haveCommonValue(values, otherValues []string) bool {
+	for _, value := range values {
+		for _, otherValue := range otherValues {
+			if strings.EqualFold(value, otherValue) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reads the year from dates like "1997", "1997-05-21" or "1997/05"
+func getYear(date *string) (int, bool) {
+	if date == nil || len(*date) < 4 {
+		return 0, false
+	}
+	year, err := strconv.Atoi((*date)[:4])
+	return year, err == nil
+}
@@ -0,0 +1,81 @@
+package autodj
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+func newTrack(filePath, artist, genre, date string) database.AudioFileMetadata {
+	return database.AudioFileMetadata{FilePath: filePath, Artist: []string{artist}, Genre: &genre, Date: &date}
+}
+
+// searches the fields of the library the way Meilisearch does, any field containing the query matches
+func searchLibrary(library []database.AudioFileMetadata) func(query string, limit int64) ([]database.AudioFileMetadata, error) {
+	return func(query string, limit int64) ([]database.AudioFileMetadata, error) {
+		results := []database.AudioFileMetadata{}
+		for _, track := range library {
+			fields := strings.Join(append(track.Artist, *track.Genre, *track.Date, track.FilePath), " ")
+			if strings.Contains(strings.ToLower(fields), strings.ToLower(query)) && int64(len(results)) < limit {
+				results = append(results, track)
+			}
+		}
+		return results, nil
+	}
+}
+
+func TestChoose(t *testing.T) {
+	library := []database.AudioFileMetadata{
+		newTrack("/music/a1.flac", "Alpha", "Jazz", "1995"),
+		newTrack("/music/a2.flac", "Alpha", "Jazz", "1996-04-01"),
+		newTrack("/music/a3.flac", "Alphaville", "Pop", "1984"),
+		newTrack("/music/b1.flac", "Beta", "Jazz", "2010"),
+		newTrack("/music/c1.flac", "Gamma", "Rock", "1997"),
+	}
+	seed := library[0]
+	testCases := []struct {
+		settings Settings
+		count    int
+		expected []string
+	}{
+		{Settings{Strategy: StrategyArtist}, 1, []string{"/music/a2.flac"}},
+		{Settings{Strategy: StrategyGenre}, 2, []string{"/music/a2.flac", "/music/b1.flac"}},
+		{Settings{Strategy: StrategyDate, DateRange: 2}, 2, []string{"/music/a2.flac", "/music/c1.flac"}},
+		{Settings{Strategy: StrategyArtist, SeedQuery: "rock"}, 5, []string{"/music/a2.flac", "/music/c1.flac"}},
+		{Settings{Strategy: StrategyGenre}, 1, nil}, // either of the jazz tracks
+	}
+	for _, testCase := range testCases {
+		selector := NewSelector(searchLibrary(library), testCase.settings)
+		filePaths, err := selector.Choose([]database.AudioFileMetadata{seed}, map[string]bool{seed.FilePath: true}, testCase.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testCase.expected == nil {
+			if len(filePaths) != testCase.count {
+				t.Errorf("%+v: expected %d tracks, got: %v", testCase.settings, testCase.count, filePaths)
+			}
+			continue
+		}
+		slices.Sort(filePaths)
+		if !slices.Equal(filePaths, testCase.expected) {
+			t.Errorf("%+v: expected: %v, got: %v", testCase.settings, testCase.expected, filePaths)
+		}
+	}
+
+	// without any recent plays the seed query is searched, an empty one matches the whole library
+	selector := NewSelector(searchLibrary(library), Settings{Strategy: StrategyArtist})
+	if filePaths, _ := selector.Choose(nil, map[string]bool{}, 10); len(filePaths) != len(library) {
+		t.Errorf("expected the whole library, got: %v", filePaths)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	if strategy, err := ParseStrategy("Album_Artist"); err != nil || strategy != StrategyAlbumArtist {
+		t.Errorf("expected album_artist, got: %s, error: %v", strategy, err)
+	}
+	if _, err := ParseStrategy("mood"); err == nil {
+		t.Errorf("expected an error for an unknown strategy")
+	}
+}
@@ -24,13 +24,60 @@ type DatabaseConfig struct {
 }
 
 type AudioConfig struct {
-	ScanDirectories []string `yaml:"scan_directories"`
-	ScanFormats     []string `yaml:"scan_formats"`
+	ScanDirectories   []string `yaml:"scan_directories"`
+	ScanFormats       []string `yaml:"scan_formats"`
+	PlaylistDirectory string   `yaml:"playlist_directory"` // stored playlists are kept here as M3U files, they are disabled when empty
+}
+
+type ServerConfig struct {
+	Mode string `yaml:"mode"` // "go-mpd" (default) or "mpd" for MPD protocol compatibility
+}
+
+type ReplayGainConfig struct {
+	Mode          string  `yaml:"mode"`           // "off" (default), "track", "album" or "auto"
+	Preamp        float64 `yaml:"preamp"`         // dB
+	AllowClipping bool    `yaml:"allow_clipping"` // by default the gain is lowered so that peaks do not clip
+}
+
+type PlayerConfig struct {
+	Crossfade                int              `yaml:"crossfade"` // seconds of overlap between consecutive tracks, 0 for gapless playback
+	SkipCrossfadeWithinAlbum bool             `yaml:"skip_crossfade_within_album"`
+	ReplayGain               ReplayGainConfig `yaml:"replay_gain"`
+}
+
+type StateConfig struct {
+	File           string `yaml:"file"`            // where the player state is kept across restarts, nothing is persisted when empty
+	ResumePlayback bool   `yaml:"resume_playback"` // keep playing after a restart instead of starting paused
+}
+
+type HistoryConfig struct {
+	File string `yaml:"file"` // listens are appended to this file, no history is kept when empty
+}
+
+type ScrobblerConfig struct {
+	Endpoint  string `yaml:"endpoint"`   // a ListenBrainz compatible API, ListenBrainz itself when empty
+	Token     string `yaml:"token"`      // user token for the API, nothing is scrobbled when empty
+	QueueFile string `yaml:"queue_file"` // listens which were not submitted yet are kept here, only in memory when empty
+}
+
+type AutoDJConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	MinimumQueued    int    `yaml:"minimum_queued"`     // tracks are appended when fewer than this many are left after the current one, 3 when 0
+	Strategy         string `yaml:"strategy"`           // "artist" (default), "album_artist", "genre" or "date"
+	SeedQuery        string `yaml:"seed_query"`         // searched when nothing fits the recent plays, the whole library when empty
+	AvoidRecentHours int    `yaml:"avoid_recent_hours"` // tracks played this recently are not chosen, 24 when 0
+	DateRange        int    `yaml:"date_range"`         // years before and after a recent play, for the date strategy
 }
 
 type Config struct {
-	Database DatabaseConfig `yaml:"database"`
-	Audio    AudioConfig    `yaml:"audio"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Audio     AudioConfig     `yaml:"audio"`
+	Server    ServerConfig    `yaml:"server"`
+	Player    PlayerConfig    `yaml:"player"`
+	State     StateConfig     `yaml:"state"`
+	History   HistoryConfig   `yaml:"history"`
+	Scrobbler ScrobblerConfig `yaml:"scrobbler"`
+	AutoDJ    AutoDJConfig    `yaml:"auto_dj"`
 }
 
 func GetBaseConfiguration() (*Config, error) {
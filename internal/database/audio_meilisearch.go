@@ -3,6 +3,9 @@ package database
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"path/filepath"
+	"time"
 
 	"github.com/arpitpandey992/go-mpd/internal/config"
 	"github.com/meilisearch/meilisearch-go"
@@ -53,3 +56,46 @@ func (amc *AudioMeilisearchClient) SearchAudioFiles(searchKey string, limit int6
 
 	return audioMetadataList, nil
 }
+
+// FindAudioFileByPath returns the indexed metadata of the file at filePath, or nil if the file is not in the index
+func (amc *AudioMeilisearchClient) FindAudioFileByPath(filePath string) (*AudioFileMetadata, error) {
+	filePath = filepath.Clean(filePath)
+	results, err := amc.SearchAudioFiles(filepath.Base(filePath), 20)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if filepath.Clean(result.FilePath) == filePath {
+			return &result, nil
+		}
+	}
+	return nil, nil
+}
+
+// WatchForUpdates polls the index every interval and calls onUpdate whenever the index was modified since the last poll.
+// Polling continues until the returned stop function is called
+func (amc *AudioMeilisearchClient) WatchForUpdates(interval time.Duration, onUpdate func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		var lastUpdatedAt time.Time
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			info, err := amc.index.FetchInfo()
+			if err != nil {
+				log.Printf("could not fetch index info: %v", err)
+			} else {
+				if !lastUpdatedAt.IsZero() && !info.UpdatedAt.Equal(lastUpdatedAt) {
+					onUpdate()
+				}
+				lastUpdatedAt = info.UpdatedAt
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,127 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	minimumTrackLength = 30 * time.Second // shorter tracks are never recorded, like jingles or interludes
+	maximumThreshold   = 4 * time.Minute  // long tracks count as listened to after this long
+)
+
+// Record is a single listen of a track. The fields follow what scrobbling services expect, so records can be submitted later on
+type Record struct {
+	PlayedAt    time.Time     `json:"played_at"` // when the track started playing
+	FilePath    string        `json:"file_path"`
+	Title       []string      `json:"title,omitempty"`
+	Artist      []string      `json:"artist,omitempty"`
+	Album       []string      `json:"album,omitempty"`
+	AlbumArtist []string      `json:"album_artist,omitempty"`
+	TrackNumber *int          `json:"track_number,omitempty"`
+	Duration    time.Duration `json:"duration"` // length of the track, 0 when unknown
+	Played      time.Duration `json:"played"`   // how long the track was actually listened to
+}
+
+// IsListen reports whether playing a track of the given duration for played counts as listening to it:
+// half of the track or four minutes, whichever comes first. Tracks shorter than 30 seconds never count
+func IsListen(duration, played time.Duration) bool {
+	if duration > 0 && duration < minimumTrackLength {
+		return false
+	}
+	threshold := maximumThreshold
+	if duration > 0 {
+		threshold = min(duration/2, maximumThreshold)
+	}
+	return played >= threshold
+}
+
+// Store is an append-only history file with one JSON record per line, oldest first
+type Store struct {
+	filePath string
+	lock     sync.Mutex
+}
+
+func NewStore(filePath string) *Store {
+	return &Store{filePath: filePath}
+}
+
+// Append adds a record to the end of the history
+func (store *Store) Append(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshalling history record: %w", err)
+	}
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	err = os.MkdirAll(filepath.Dir(store.filePath), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating history directory: %w", err)
+	}
+	file, err := os.OpenFile(store.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening history file: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("error writing history file: %w", err)
+	}
+	return nil
+}
+
+// Last returns the latest count records, oldest first
+func (store *Store) Last(count int) ([]Record, error) {
+	records, err := store.read(func(Record) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	return records[max(len(records)-count, 0):], nil
+}
+
+// Between returns the records of tracks which started playing in [from, to), oldest first
+func (store *Store) Between(from, to time.Time) ([]Record, error) {
+	return store.read(func(record Record) bool {
+		return !record.PlayedAt.Before(from) && record.PlayedAt.Before(to)
+	})
+}
+
+// reads the records for which keep returns true. Lines which can not be parsed, like one cut off by a crash, are skipped
+func (store *Store) read(keep func(Record) bool) ([]Record, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	records := []Record{}
+	file, err := os.Open(store.filePath)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening history file: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			log.Printf("skipping invalid history record at %s:%d, error: %v", store.filePath, lineNumber, err)
+			continue
+		}
+		if keep(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading history file: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsListen(t *testing.T) {
+	testCases := []struct {
+		duration time.Duration
+		played   time.Duration
+		expected bool
+	}{
+		{time.Minute * 3, time.Second * 89, false},
+		{time.Minute * 3, time.Second * 90, true},
+		{time.Minute * 20, time.Minute * 4, true},
+		{time.Second * 20, time.Second * 20, false},
+		{0, time.Minute * 3, false},
+		{0, time.Minute * 4, true},
+	}
+	for _, testCase := range testCases {
+		if IsListen(testCase.duration, testCase.played) != testCase.expected {
+			t.Errorf("%+v: expected %v", testCase, testCase.expected)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "history", "history.jsonl")
+	store := NewStore(filePath)
+	if records, err := store.Last(5); err != nil || len(records) != 0 {
+		t.Fatalf("expected an empty history, got: %v, error: %v", records, err)
+	}
+
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		checkError(store.Append(Record{
+			PlayedAt: start.Add(time.Hour * time.Duration(i)),
+			FilePath: filepath.Join("music", string(rune('a'+i))+".mp3"),
+			Title:    []string{"track"},
+			Duration: time.Minute * 3,
+			Played:   time.Minute * 2,
+		}), t)
+	}
+	// a record cut off by a crash is skipped
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	checkError(err, t)
+	_, err = file.WriteString(`{"played_at": "2024-05`)
+	checkError(err, t)
+	checkError(file.Close(), t)
+
+	records, err := store.Last(2)
+	checkError(err, t)
+	if len(records) != 2 || records[0].FilePath != filepath.Join("music", "b.mp3") || records[1].Played != time.Minute*2 {
+		t.Errorf("unexpected last records: %+v", records)
+	}
+	records, err = store.Between(start, start.Add(time.Hour*2))
+	checkError(err, t)
+	if len(records) != 2 || !records[0].PlayedAt.Equal(start) {
+		t.Errorf("unexpected records between dates: %+v", records)
+	}
+}
+
+func checkError(err error, t *testing.T) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
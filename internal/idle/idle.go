@@ -0,0 +1,146 @@
+package idle
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Subsystem is a part of the daemon whose state changes can be waited upon, names follow the MPD protocol
+type Subsystem string
+
+const (
+	SubsystemDatabase       Subsystem = "database"        // the audio index was updated
+	SubsystemPlaylist       Subsystem = "playlist"        // the playback queue was modified
+	SubsystemStoredPlaylist Subsystem = "stored_playlist" // a stored playlist was created, modified, renamed or deleted
+	SubsystemPlayer         Subsystem = "player"          // play, pause, stop, seek or a track change
+	SubsystemMixer          Subsystem = "mixer"           // volume changed
+	SubsystemOptions        Subsystem = "options"         // playback options like repeat or random changed
+	SubsystemOutput         Subsystem = "output"          // an audio output was enabled, disabled or reassigned
+	SubsystemPartition      Subsystem = "partition"       // a partition was added or removed
+)
+
+var AllSubsystems = []Subsystem{
+	SubsystemDatabase,
+	SubsystemPlaylist,
+	SubsystemStoredPlaylist,
+	SubsystemPlayer,
+	SubsystemMixer,
+	SubsystemOptions,
+	SubsystemOutput,
+	SubsystemPartition,
+}
+
+func ParseSubsystem(name string) (Subsystem, error) {
+	for _, subsystem := range AllSubsystems {
+		if string(subsystem) == name {
+			return subsystem, nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized idle event: %s", name)
+}
+
+// Notifier fans out change notifications to every registered Listener
+type Notifier struct {
+	listeners     map[*Listener]struct{}
+	listenersLock sync.Mutex
+}
+
+func NewNotifier() *Notifier {
+	return &Notifier{
+		listeners: map[*Listener]struct{}{},
+	}
+}
+
+// Notify marks the given subsystems as changed for all listeners and wakes up the ones waiting
+func (n *Notifier) Notify(subsystems ...Subsystem) {
+	n.listenersLock.Lock()
+	defer n.listenersLock.Unlock()
+	for listener := range n.listeners {
+		listener.markChanged(subsystems)
+	}
+}
+
+// Listen registers a new listener. Changes are accumulated from this point onwards until they are
+// returned by Wait, so nothing that happens between two Wait calls is lost
+func (n *Notifier) Listen() *Listener {
+	listener := &Listener{
+		notifier: n,
+		pending:  map[Subsystem]bool{},
+		wakeup:   make(chan struct{}, 1),
+	}
+	n.listenersLock.Lock()
+	defer n.listenersLock.Unlock()
+	n.listeners[listener] = struct{}{}
+	return listener
+}
+
+type Listener struct {
+	notifier    *Notifier
+	pending     map[Subsystem]bool
+	pendingLock sync.Mutex
+	wakeup      chan struct{}
+}
+
+func (l *Listener) markChanged(subsystems []Subsystem) {
+	l.pendingLock.Lock()
+	defer l.pendingLock.Unlock()
+	for _, subsystem := range subsystems {
+		l.pending[subsystem] = true
+	}
+	select {
+	case l.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// takes the pending changes out of the listener, limited to the given subsystems (all when empty)
+func (l *Listener) takeChanged(subsystems []Subsystem) []Subsystem {
+	l.pendingLock.Lock()
+	defer l.pendingLock.Unlock()
+	changed := []Subsystem{}
+	for _, subsystem := range AllSubsystems {
+		if !l.pending[subsystem] || (len(subsystems) > 0 && !containsSubsystem(subsystems, subsystem)) {
+			continue
+		}
+		delete(l.pending, subsystem)
+		changed = append(changed, subsystem)
+	}
+	return changed
+}
+
+// Wait blocks until at least one of the given subsystems (any subsystem when empty) has changed and returns
+// the changed ones. If cancel is closed first, the changes pending at that moment are returned, which may be none
+func (l *Listener) Wait(subsystems []Subsystem, cancel <-chan struct{}) []Subsystem {
+	for {
+		changed := l.takeChanged(subsystems)
+		if len(changed) > 0 {
+			return changed
+		}
+		select {
+		case <-l.wakeup:
+		case <-cancel:
+			return l.takeChanged(subsystems)
+		}
+	}
+}
+
+// Poll returns and clears the pending changes of the given subsystems (any subsystem when empty) without blocking
+func (l *Listener) Poll(subsystems []Subsystem) []Subsystem {
+	return l.takeChanged(subsystems)
+}
+
+// Close unregisters the listener from its notifier
+func (l *Listener) Close() {
+	l.notifier.listenersLock.Lock()
+	defer l.notifier.listenersLock.Unlock()
+	delete(l.notifier.listeners, l)
+}
+
+func containsSubsystem(subsystems []Subsystem, subsystem Subsystem) bool {
+	for _, s := range subsystems {
+		if s == subsystem {
+			return true
+		}
+	}
+	return false
+}
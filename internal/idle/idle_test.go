@@ -0,0 +1,68 @@
+package idle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWaitReturnsAccumulatedChanges(t *testing.T) {
+	notifier := NewNotifier()
+	listener := notifier.Listen()
+	defer listener.Close()
+
+	notifier.Notify(SubsystemPlayer)
+	notifier.Notify(SubsystemPlaylist, SubsystemPlayer)
+	changed := listener.Wait(nil, nil)
+	expected := []Subsystem{SubsystemPlaylist, SubsystemPlayer}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Errorf("expected: %v, got: %v", expected, changed)
+	}
+}
+
+func TestWaitFiltersSubsystems(t *testing.T) {
+	notifier := NewNotifier()
+	listener := notifier.Listen()
+	defer listener.Close()
+
+	result := make(chan []Subsystem)
+	go func() {
+		result <- listener.Wait([]Subsystem{SubsystemMixer}, nil)
+	}()
+	notifier.Notify(SubsystemPlayer)
+	select {
+	case changed := <-result:
+		t.Fatalf("woke up for an unrequested subsystem: %v", changed)
+	case <-time.After(50 * time.Millisecond):
+	}
+	notifier.Notify(SubsystemMixer)
+	changed := <-result
+	if !reflect.DeepEqual(changed, []Subsystem{SubsystemMixer}) {
+		t.Errorf("expected only mixer, got: %v", changed)
+	}
+	// the player change was not consumed by the filtered wait
+	changed = listener.Wait(nil, nil)
+	if !reflect.DeepEqual(changed, []Subsystem{SubsystemPlayer}) {
+		t.Errorf("expected the pending player change, got: %v", changed)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	notifier := NewNotifier()
+	listener := notifier.Listen()
+	cancel := make(chan struct{})
+	result := make(chan []Subsystem)
+	go func() {
+		result <- listener.Wait(nil, cancel)
+	}()
+	close(cancel)
+	if changed := <-result; len(changed) != 0 {
+		t.Errorf("expected no changes after cancel, got: %v", changed)
+	}
+
+	listener.Close()
+	notifier.Notify(SubsystemDatabase)
+	if changed := listener.takeChanged(nil); len(changed) != 0 {
+		t.Errorf("closed listener should not receive changes, got: %v", changed)
+	}
+}
@@ -0,0 +1,145 @@
+package playbackmanager
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// EventType tells what happened in an Event
+type EventType string
+
+const (
+	EventTrackStarted  EventType = "track_started"  // a track started playing from its beginning or from where it was restored
+	EventTrackFinished EventType = "track_finished" // a track stopped being played, because it ended or because it was stopped or skipped
+	EventPaused        EventType = "paused"
+	EventResumed       EventType = "resumed"
+	EventSeeked        EventType = "seeked"
+	EventLoopSet       EventType = "loop_set" // the current track started looping, or its loop was moved
+	EventLoopCleared   EventType = "loop_cleared"
+	EventAudibility    EventType = "audibility" // the player got or lost its enabled output, Audible tells which
+	EventQueueChanged  EventType = "queue_changed"
+	EventQueueEnded    EventType = "queue_ended" // playback reached the end of the queue, because the last track finished or was skipped
+	EventDecodeError   EventType = "decode_error"
+)
+
+const defaultEventBufferSize = 64
+
+// Event is published by the playback manager whenever its playback or its queue changes
+type Event struct {
+	Type      EventType
+	Time      time.Time
+	Entry     QueueEntry    // the track the event is about, empty for queue events
+	Elapsed   time.Duration // position within the track when the event happened
+	From      time.Duration // for EventSeeked, the position within the track before seeking
+	Audible   bool          // for track events, whether an enabled output plays the track
+	Duration  time.Duration // length of the track
+	Completed bool          // for EventTrackFinished and EventQueueEnded, whether the track played until its end
+	Err       error         // for EventDecodeError
+}
+
+// Subscription receives the events published after it was created, in order. Publishing never waits for subscribers,
+// so events are dropped when the buffer of a subscriber is full
+type Subscription struct {
+	Events <-chan Event
+
+	bus     *eventBus
+	events  chan Event
+	dropped int
+}
+
+// Unsubscribe stops the delivery of events and closes the Events channel
+func (subscription *Subscription) Unsubscribe() {
+	subscription.bus.unsubscribe(subscription)
+}
+
+// Dropped returns how many events were lost because the buffer was full
+func (subscription *Subscription) Dropped() int {
+	subscription.bus.subscriptionsLock.Lock()
+	defer subscription.bus.subscriptionsLock.Unlock()
+	return subscription.dropped
+}
+
+type eventBus struct {
+	subscriptions     map[*Subscription]struct{}
+	subscriptionsLock sync.Mutex
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscriptions: map[*Subscription]struct{}{}}
+}
+
+func (bus *eventBus) subscribe(bufferSize int) *Subscription {
+	if bufferSize <= 0 {
+		bufferSize = defaultEventBufferSize
+	}
+	events := make(chan Event, bufferSize)
+	subscription := &Subscription{Events: events, bus: bus, events: events}
+	bus.subscriptionsLock.Lock()
+	defer bus.subscriptionsLock.Unlock()
+	bus.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+func (bus *eventBus) unsubscribe(subscription *Subscription) {
+	bus.subscriptionsLock.Lock()
+	defer bus.subscriptionsLock.Unlock()
+	if _, subscribed := bus.subscriptions[subscription]; !subscribed {
+		return
+	}
+	delete(bus.subscriptions, subscription)
+	close(subscription.events)
+}
+
+func (bus *eventBus) publish(event Event) {
+	bus.subscriptionsLock.Lock()
+	defer bus.subscriptionsLock.Unlock()
+	for subscription := range bus.subscriptions {
+		select {
+		case subscription.events <- event:
+		default:
+			subscription.dropped++
+			log.Printf("event subscriber is not keeping up, dropped: %s", event.Type)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events of the playback manager, buffering up to bufferSize events
+// (a default size when it is 0). It has to be unsubscribed once it is not needed anymore
+func (pm *PlaybackManager) Subscribe(bufferSize int) *Subscription {
+	return pm.events.subscribe(bufferSize)
+}
+
+// publishes an event about the current track, must be called with the locks held
+func (pm *PlaybackManager) publishTrackEvent(eventType EventType) {
+	pm.events.publish(pm.newTrackEvent(eventType))
+}
+
+func (pm *PlaybackManager) publishSeeked(from time.Duration) {
+	event := pm.newTrackEvent(EventSeeked)
+	event.From = from
+	pm.events.publish(event)
+}
+
+func (pm *PlaybackManager) newTrackEvent(eventType EventType) Event {
+	event := Event{Type: eventType, Time: time.Now(), Audible: pm.audible}
+	if pm.QueuePosition < len(pm.playbackQueue) {
+		event.Entry = pm.playbackQueue[pm.QueuePosition]
+	}
+	if pm.audioPlayer != nil {
+		event.Elapsed, event.Duration = pm.audioPlayer.GetCurrentPosition(), pm.audioPlayer.GetDuration()
+	}
+	return event
+}
+
+func (pm *PlaybackManager) publishTrackFinished(entry QueueEntry, elapsed, duration time.Duration, completed bool) {
+	pm.events.publish(Event{Type: EventTrackFinished, Time: time.Now(), Entry: entry, Elapsed: elapsed, Duration: duration, Completed: completed})
+}
+
+func (pm *PlaybackManager) publishQueueEnded(completed bool) {
+	pm.events.publish(Event{Type: EventQueueEnded, Time: time.Now(), Completed: completed})
+}
+
+func (pm *PlaybackManager) publishQueueEvent(eventType EventType) {
+	pm.events.publish(Event{Type: eventType, Time: time.Now()})
+}
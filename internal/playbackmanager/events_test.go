@@ -0,0 +1,86 @@
+package playbackmanager
+
+import (
+	"testing"
+	"time"
+)
+
+// waits for the next event of the given type, skipping the others, and fails the test if it does not arrive in time
+func waitForEvent(t *testing.T, subscription *Subscription, eventType EventType, timeout time.Duration) Event {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				t.Fatalf("the subscription was closed while waiting for event %v", eventType)
+			}
+			if event.Type == eventType {
+				return event
+			}
+		case <-deadline:
+			t.Fatalf("timed out after %v waiting for event %v", timeout, eventType)
+		}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := newEventBus()
+	subscription := bus.subscribe(2)
+	for i := 0; i < 3; i++ {
+		bus.publish(Event{Type: EventQueueChanged})
+	}
+	if subscription.Dropped() != 1 {
+		t.Errorf("expected 1 dropped event, got: %d", subscription.Dropped())
+	}
+	subscription.Unsubscribe()
+	subscription.Unsubscribe()
+	bus.publish(Event{Type: EventQueueChanged})
+	received := 0
+	for range subscription.Events {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("expected the buffered events before the channel is closed, got: %d", received)
+	}
+}
+
+func TestPlaybackEvents(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	subscription := playbackManager.Subscribe(0)
+	defer subscription.Unsubscribe()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3"), t)
+	checkError(playbackManager.Play(), t)
+	checkError(playbackManager.Pause(), t)
+	checkError(playbackManager.Seek(2*time.Second), t)
+	checkError(playbackManager.Play(), t)
+
+	expected := []EventType{EventQueueChanged, EventTrackStarted, EventPaused, EventSeeked, EventResumed, EventTrackFinished, EventQueueEnded}
+	for _, eventType := range expected {
+		select {
+		case event := <-subscription.Events:
+			if event.Type != eventType {
+				t.Fatalf("expected: %s, got: %+v", eventType, event)
+			}
+			if eventType == EventSeeked && event.Elapsed != 2*time.Second {
+				t.Errorf("expected the seeked event at 2s, got: %v", event.Elapsed)
+			}
+			if eventType == EventTrackFinished && (!event.Completed || event.Entry.URI != "../../music/sample-3s.mp3") {
+				t.Errorf("expected the track to finish completely, got: %+v", event)
+			}
+			if eventType == EventQueueEnded && !event.Completed {
+				t.Errorf("expected the queue to run out during playback, got: %+v", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for: %s", eventType)
+		}
+	}
+
+	// skipping the last track ends the queue without it running out
+	checkError(playbackManager.PlayQueuePosition(0), t)
+	checkError(playbackManager.Pause(), t)
+	checkError(playbackManager.Next(), t)
+	if event := waitForEvent(t, subscription, EventQueueEnded, time.Second); event.Completed {
+		t.Errorf("expected the queue to end by skipping, got: %+v", event)
+	}
+}
@@ -0,0 +1,107 @@
+package playbackmanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+// LoopRange is the part of the current track which is played over and over, from A until B
+type LoopRange struct {
+	A time.Duration
+	B time.Duration
+}
+
+// SetLoop loops the current track between the points of the range until the loop is cleared or another track is played.
+// The track does not finish while it loops, so the queue does not advance. The sleep timer takes precedence over a loop:
+// a track can not be looped while playback is set to stop after it, and setting that clears the loop
+func (pm *PlaybackManager) SetLoop(loop LoopRange) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	return pm.setLoop(loop)
+}
+
+// MarkLoopStart marks the current position as point A of a loop, which starts once point B is marked
+func (pm *PlaybackManager) MarkLoopStart() (time.Duration, error) {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if pm.audioPlayer == nil {
+		return 0, fmt.Errorf("no active audio file in queue")
+	}
+	pm.loopStart, pm.loopStartPlayer = pm.audioPlayer.GetCurrentPosition(), pm.audioPlayer
+	return pm.loopStart, nil
+}
+
+// MarkLoopEnd marks the current position as point B and starts looping back to the point A marked before
+func (pm *PlaybackManager) MarkLoopEnd() (LoopRange, error) {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if pm.audioPlayer == nil {
+		return LoopRange{}, fmt.Errorf("no active audio file in queue")
+	}
+	if pm.loopStartPlayer != pm.audioPlayer {
+		return LoopRange{}, fmt.Errorf("point A of the loop is not marked in the current track")
+	}
+	loop := LoopRange{A: pm.loopStart, B: pm.audioPlayer.GetCurrentPosition()}
+	return loop, pm.setLoop(loop)
+}
+
+// ClearLoop lets the current track play on until its end, and forgets a marked point A
+func (pm *PlaybackManager) ClearLoop() {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	pm.loopStartPlayer = nil
+	if pm.audioPlayer == nil {
+		return
+	}
+	pm.clearLoop()
+}
+
+// GetLoop returns the loop of the current track, looping is false when the track does not loop
+func (pm *PlaybackManager) GetLoop() (loop LoopRange, looping bool) {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	if pm.audioPlayer == nil {
+		return LoopRange{}, false
+	}
+	loop.A, loop.B, looping = pm.audioPlayer.GetLoop()
+	return loop, looping
+}
+
+func (pm *PlaybackManager) setLoop(loop LoopRange) error {
+	if pm.audioPlayer == nil {
+		return fmt.Errorf("no active audio file in queue")
+	}
+	if pm.sleepTimer.StopAfterCurrent || pm.sleepTimer.StopAfterAlbum {
+		return fmt.Errorf("playback stops after the current track, cancel the sleep timer to loop it")
+	}
+	err := pm.audioPlayer.SetLoop(loop.A, loop.B)
+	if err != nil {
+		return err
+	}
+	if pm.options.Crossfade > 0 {
+		// the preloaded track may already have started fading in
+		pm.reloadNextTrack()
+	}
+	pm.publishTrackEvent(EventLoopSet)
+	pm.notifier.Notify(idle.SubsystemPlayer)
+	return nil
+}
+
+func (pm *PlaybackManager) clearLoop() {
+	if _, _, looping := pm.audioPlayer.GetLoop(); !looping {
+		return
+	}
+	pm.audioPlayer.ClearLoop()
+	pm.publishTrackEvent(EventLoopCleared)
+	pm.notifier.Notify(idle.SubsystemPlayer)
+}
@@ -0,0 +1,55 @@
+package playbackmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoop(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3"), t)
+	if err := playbackManager.SetLoop(LoopRange{A: time.Second, B: 2 * time.Second}); err == nil {
+		t.Errorf("expected an error for looping without a track")
+	}
+	checkError(playbackManager.Play(), t)
+	defer playbackManager.Stop()
+	if err := playbackManager.SetLoop(LoopRange{A: 2 * time.Second, B: time.Second}); err == nil {
+		t.Errorf("expected an error for B before A")
+	}
+	if _, err := playbackManager.MarkLoopEnd(); err == nil {
+		t.Errorf("expected an error for marking B without A")
+	}
+
+	// playback would have passed B by now without the loop
+	checkError(playbackManager.SetLoop(LoopRange{A: 2500 * time.Millisecond, B: 3 * time.Second}), t)
+	time.Sleep(3500 * time.Millisecond)
+	if loop, looping := playbackManager.GetLoop(); !looping || loop.A != 2500*time.Millisecond || playbackManager.QueuePosition != 0 {
+		t.Fatalf("expected the first track to keep looping, at position %d, got loop: %v", playbackManager.QueuePosition, loop)
+	}
+	if elapsed := playbackManager.GetStatus().Elapsed; elapsed < 2*time.Second {
+		t.Errorf("expected to stay between A and B, at: %v", elapsed)
+	}
+
+	// the sleep timer takes precedence, a track which playback stops after can not loop
+	playbackManager.SetStopAfterCurrent(true)
+	if _, looping := playbackManager.GetLoop(); looping {
+		t.Errorf("expected stopping after the current track to clear the loop")
+	}
+	if err := playbackManager.SetLoop(LoopRange{A: 2500 * time.Millisecond, B: 3 * time.Second}); err == nil {
+		t.Errorf("expected an error for looping a track which playback stops after")
+	}
+	playbackManager.SetStopAfterCurrent(false)
+	subscription := playbackManager.Subscribe(0)
+	defer subscription.Unsubscribe()
+	checkError(playbackManager.SetLoop(LoopRange{A: 2500 * time.Millisecond, B: 3 * time.Second}), t)
+	waitForEvent(t, subscription, EventLoopSet, time.Second)
+
+	playbackManager.ClearLoop()
+	waitForEvent(t, subscription, EventLoopCleared, time.Second)
+	if event := waitForEvent(t, subscription, EventTrackStarted, 5*time.Second); event.Entry.ID != playbackManager.GetQueue()[1].ID {
+		t.Errorf("expected the queue to advance once the loop is cleared, got: %+v", event.Entry)
+	}
+	if _, looping := playbackManager.GetLoop(); looping {
+		t.Errorf("expected the next track not to loop")
+	}
+}
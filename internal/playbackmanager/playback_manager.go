@@ -9,6 +9,9 @@ import (
 	"time"
 
 	"github.com/arpitpandey992/go-mpd/internal/audioplayer"
+	"github.com/arpitpandey992/go-mpd/internal/database"
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/replaygain"
 	"github.com/gopxl/beep"
 	"github.com/gopxl/beep/speaker"
 )
@@ -26,62 +29,126 @@ look at individual TODO marked around code
 
 const (
 	baseSampleRate = 44100 //TODO: make this a quality setting instead since this would mean we are gonna downsample higher sample rate files to 44100 only
+
+	minimumCrossfadeTrackRatio = 2 // tracks shorter than this many times the crossfade duration are not crossfaded
+)
+
+// the speaker is shared by every playback manager in the process. It is initialized only once since
+// speaker.Init deadlocks when it closes a speaker which is about to pull new samples
+var initSpeakerOnce sync.Once
+
+type PlaybackState string
+
+const (
+	PlaybackStatePlaying PlaybackState = "playing"
+	PlaybackStatePaused  PlaybackState = "paused"
+	PlaybackStateStopped PlaybackState = "stopped"
 )
 
+// PlaybackStatus is a snapshot of what the playback manager is currently doing
+type PlaybackStatus struct {
+	State         PlaybackState
+	Options       PlaybackOptions
+	Volume        int
+	Muted         bool
+	QueuePosition int // equal to QueueLength when there is no current track
+	QueueLength   int
+	QueueVersion  int // incremented on every modification of the queue
+	CurrentId     int // ID of the entry at QueuePosition, -1 when there is no current track
+
+	// the fields below are only set while a track is playing or paused
+	CurrentFilePath string
+	Elapsed         time.Duration
+	Duration        time.Duration
+	SampleRate      int // native sample rate of the track, before resampling
+	BitDepth        int
+	Channels        int
+}
+
 type PlaybackManager struct {
 	QueuePosition int
 
-	QueuePlaybackFinished chan bool
+	audioPlayer      *audioplayer.AudioPlayer
+	sequencer        *audioplayer.GaplessSequencer // the only streamer played on the speaker, splices tracks without gaps
+	preloadedPlayer  *audioplayer.AudioPlayer      // audio player queued in the sequencer after the current one
+	preloadedEntryId int
+	volumeMixer      *audioplayer.VolumeMixer // applies the volume to the output of the sequencer
+	volume           int
+	muted            bool
+	audible          bool // whether an enabled output plays the audio
+	playbackQueue    []QueueEntry
+	lastQueueEntryId int
+	queueVersion     int
+	queueHistory     *queueHistory // modifications of the queue which can be undone
 
-	audioPlayer   *audioplayer.AudioPlayer
-	playbackQueue []string // TODO -> move from slice of string to a slice of some interface object for flexibility
+	options              PlaybackOptions
+	sleepTimer           SleepTimer
+	sleepTimerCancel     chan struct{} // closed to stop the countdown of the sleep timer, nil when no time is set
+	sleepFadeGain        float64       // lowers the volume while the sleep timer fades out, 1 otherwise
+	randomRoundPlayedIds map[int]bool  // entries already played in the current round of random playback
 
 	audioPlayerLock   sync.Mutex
 	playbackQueueLock sync.Mutex
+
+	notifier            *idle.Notifier // publishes player and playlist changes
+	events              *eventBus
+	currentTrackStarted bool                     // whether the current track was played since it was loaded, which decides between started and resumed events
+	loopStart           time.Duration            // point A of a loop, marked with MarkLoopStart
+	loopStartPlayer     *audioplayer.AudioPlayer // the audio player point A was marked in, nil when it is not marked
+
+	metadataResolver   func(filePath string) *database.AudioFileMetadata // looks up tracks in the audio database, nil when there is none
+	replayGainSettings replaygain.Settings
 }
 
 func CreatePlaybackManager() *PlaybackManager {
 	playbackManager := PlaybackManager{
-		QueuePosition:         0,
-		QueuePlaybackFinished: make(chan bool),
-		playbackQueue:         []string{},
-		audioPlayer:           nil,
-		audioPlayerLock:       sync.Mutex{},
-		playbackQueueLock:     sync.Mutex{},
+		QueuePosition:        0,
+		playbackQueue:        []QueueEntry{},
+		audioPlayer:          nil,
+		audioPlayerLock:      sync.Mutex{},
+		playbackQueueLock:    sync.Mutex{},
+		notifier:             idle.NewNotifier(),
+		events:               newEventBus(),
+		queueHistory:         newQueueHistory(defaultQueueHistorySize),
+		randomRoundPlayedIds: map[int]bool{},
+		volume:               defaultVolume,
+		audible:              true,
+		sleepFadeGain:        1,
+		replayGainSettings:   replaygain.Settings{Mode: replaygain.ModeOff, PreventClipping: true},
 	}
 	speakerSampleRate := beep.SampleRate(baseSampleRate)
-	_ = speaker.Init(speakerSampleRate, speakerSampleRate.N(time.Second/10))
+	initSpeakerOnce.Do(func() {
+		_ = speaker.Init(speakerSampleRate, speakerSampleRate.N(time.Second/10))
+	})
+	playbackManager.sequencer = audioplayer.NewGaplessSequencer(speakerSampleRate)
+	playbackManager.volumeMixer = audioplayer.NewVolumeMixer(playbackManager.sequencer, speakerSampleRate)
+	speaker.Play(playbackManager.volumeMixer)
 	return &playbackManager
 }
 
-func (pm *PlaybackManager) AddAudioFilesToQueue(filePaths ...string) error {
+func (pm *PlaybackManager) Next() error {
+	log.Printf("debug: Next() called")
+	pm.audioPlayerLock.Lock()
 	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
 	defer pm.playbackQueueLock.Unlock()
-	unsuccessfulAdditions := make([]string, 0)
-	for _, filePath := range filePaths {
-		err := pm.addAudioFileToQueue(filePath)
-		if err != nil {
-			log.Printf("could not add: %s, error: %v", filePath, err)
-			unsuccessfulAdditions = append(unsuccessfulAdditions, filePath)
-		} else {
-			log.Printf("added: %s", filePath)
-		}
-	}
-	allFilesAddedSuccessfully := len(unsuccessfulAdditions) == 0
-	if !allFilesAddedSuccessfully {
-		return fmt.Errorf("could not add: %s", strings.Join(unsuccessfulAdditions, ","))
-	}
-	log.Print("added all files")
-	return nil
+	return pm.next()
 }
 
-func (pm *PlaybackManager) Next() error {
-	log.Printf("debug: Next() called")
+// moves on from finishedPlayer only if it is still the active audio player.
+// Another client may already have skipped or stopped the track by the time its finish callback runs
+func (pm *PlaybackManager) nextIfCurrentlyPlaying(finishedPlayer *audioplayer.AudioPlayer) error {
 	pm.audioPlayerLock.Lock()
 	pm.playbackQueueLock.Lock()
 	defer pm.audioPlayerLock.Unlock()
 	defer pm.playbackQueueLock.Unlock()
-	return pm.next()
+	if pm.audioPlayer != finishedPlayer {
+		return nil
+	}
+	if pm.preloadedPlayer == nil {
+		return pm.advance(true)
+	}
+	return pm.continueWithPreloadedTrack()
 }
 
 func (pm *PlaybackManager) Previous() error {
@@ -95,7 +162,9 @@ func (pm *PlaybackManager) Previous() error {
 
 func (pm *PlaybackManager) Play() error {
 	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
 	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
 	return pm.play()
 }
 
@@ -115,59 +184,291 @@ func (pm *PlaybackManager) Stop() error {
 	return pm.stop()
 }
 
-func (pm *PlaybackManager) Seek(seekTime time.Duration) error {
+// Close stops playback for good and releases the speaker, the playback manager can not be used afterwards
+func (pm *PlaybackManager) Close() {
 	pm.audioPlayerLock.Lock()
 	pm.playbackQueueLock.Lock()
 	defer pm.audioPlayerLock.Unlock()
 	defer pm.playbackQueueLock.Unlock()
+	if pm.audioPlayer != nil {
+		err := pm.stop()
+		if err != nil {
+			log.Printf("error while stopping playback, error: %v", err)
+		}
+	}
+	pm.stopSleepCountdown()
+	pm.volumeMixer.Close()
+}
 
-	if pm.QueuePosition == len(pm.playbackQueue) || pm.audioPlayer == nil {
-		return fmt.Errorf("no active audio file in queue")
+func (pm *PlaybackManager) PlayQueuePosition(position int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	return pm.playQueuePosition(position)
+}
+
+func (pm *PlaybackManager) playQueuePosition(position int) error {
+	if position < 0 || position >= len(pm.playbackQueue) {
+		return fmt.Errorf("invalid queue position: %d", position)
+	}
+	if pm.audioPlayer != nil {
+		err := pm.stop()
+		if err != nil {
+			return err
+		}
 	}
-	return pm.audioPlayer.Seek(seekTime)
+	pm.QueuePosition = position
+	return pm.play()
+}
+
+// SetMetadataResolver sets the function used to look up a track in the audio database, which returns nil for unknown tracks.
+// The metadata of an entry is looked up once, when it is added to the queue
+func (pm *PlaybackManager) SetMetadataResolver(metadataResolver func(filePath string) *database.AudioFileMetadata) {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	pm.metadataResolver = metadataResolver
+}
+
+// IdleNotifier returns the notifier on which the playback manager publishes player and playlist changes
+func (pm *PlaybackManager) IdleNotifier() *idle.Notifier {
+	return pm.notifier
+}
+
+func (pm *PlaybackManager) GetStatus() PlaybackStatus {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	return pm.getStatus()
+}
+
+func (pm *PlaybackManager) getStatus() PlaybackStatus {
+	status := PlaybackStatus{
+		State:         PlaybackStateStopped,
+		Options:       pm.options,
+		Volume:        pm.volume,
+		Muted:         pm.muted,
+		QueuePosition: pm.QueuePosition,
+		QueueLength:   len(pm.playbackQueue),
+		QueueVersion:  pm.queueVersion,
+		CurrentId:     pm.getCurrentEntryId(),
+	}
+	if pm.audioPlayer != nil {
+		status.State = PlaybackStatePlaying
+		if pm.audioPlayer.IsPaused() {
+			status.State = PlaybackStatePaused
+		}
+		status.CurrentFilePath = pm.playbackQueue[pm.QueuePosition].URI
+		status.Elapsed = pm.audioPlayer.GetCurrentPosition()
+		status.Duration = pm.audioPlayer.GetDuration()
+		status.SampleRate = int(pm.audioPlayer.Format.SampleRate)
+		status.BitDepth = pm.audioPlayer.Format.Precision * 8
+		status.Channels = pm.audioPlayer.Format.NumChannels
+	}
+	return status
 }
 
 func (pm *PlaybackManager) GetCurrentTrackName() string {
+	pm.playbackQueueLock.Lock()
+	defer pm.playbackQueueLock.Unlock()
+	return pm.getCurrentTrackName()
+}
+
+func (pm *PlaybackManager) getCurrentTrackName() string {
 	if pm.QueuePosition >= 0 && pm.QueuePosition < len(pm.playbackQueue) {
-		filePath := pm.playbackQueue[pm.QueuePosition]
-		return path.Base(filePath)
+		return path.Base(pm.playbackQueue[pm.QueuePosition].URI)
 	}
 	return "[Nothing in Queue]"
 }
 
 func (pm *PlaybackManager) createAudioPlayerForCurrentTrack() error {
-	log.Printf("creating audioplayer for: %s", pm.GetCurrentTrackName())
+	log.Printf("creating audioplayer for: %s", pm.getCurrentTrackName())
 	if pm.audioPlayer != nil {
 		return nil
 	}
+	ap, err := pm.createAudioPlayer(pm.playbackQueue[pm.QueuePosition])
+	if err != nil {
+		return err
+	}
+	pm.audioPlayer = ap
+	pm.currentTrackStarted = false
+	pm.sequencer.SetCurrent(ap)
+	log.Print("new audioplayer created successfully")
+	return nil
+}
+
+func (pm *PlaybackManager) createAudioPlayer(entry QueueEntry) (*audioplayer.AudioPlayer, error) {
+	var ap *audioplayer.AudioPlayer
 	doOnFinishPlaying := func() {
 		// This is ran on a separate go routine because the speaker is locked when the callback function is called. Hence, it causes deadlock as Next() also requires the speaker to be locked.
 		go func() {
-			err := pm.Next()
+			err := pm.nextIfCurrentlyPlaying(ap)
 			if err != nil {
 				log.Print(err)
 			}
 		}()
 	}
-	ap, err := audioplayer.CreateAudioPlayer(pm.playbackQueue[pm.QueuePosition], doOnFinishPlaying)
+	if !entry.IsLocal() {
+		return nil, fmt.Errorf("playing streams is not supported yet: %s", entry.URI)
+	}
+	trackRange := TrackRange{}
+	if entry.Range != nil {
+		trackRange = *entry.Range
+	}
+	ap, err := audioplayer.CreateAudioPlayerForRange(entry.URI, trackRange.Start, trackRange.End, doOnFinishPlaying)
 	if err != nil {
-		return err
+		pm.events.publish(Event{Type: EventDecodeError, Time: time.Now(), Entry: entry, Err: err})
+		return nil, err
 	}
-	pm.audioPlayer = ap
-	pm.resampleTrackIfNeeded()
-	log.Print("new audioplayer created successfully")
-	return nil
+	ap.Volume.ResetGain(pm.getReplayGainScale(entry))
+	return ap, nil
+}
+
+// preloadNextTrack queues the track which follows the current one in the sequencer, replacing any previously preloaded track.
+// It has to be called whenever the queue or the playback options change, as they decide which track comes next.
+// A preloaded track which still comes next is kept, only its gain and its crossfade are updated
+func (pm *PlaybackManager) preloadNextTrack() {
+	if pm.audioPlayer == nil {
+		pm.discardPreloadedTrack()
+		return
+	}
+	nextEntry, found := pm.getEntryToPreload()
+	if found && pm.preloadedPlayer != nil && pm.preloadedEntryId == nextEntry.ID {
+		pm.preloadedPlayer.Volume.SetGain(pm.getReplayGainScale(nextEntry))
+		pm.sequencer.SetCrossfade(pm.getCrossfadeDuration(pm.preloadedPlayer, nextEntry))
+		return
+	}
+	if !pm.discardPreloadedTrack() || !found {
+		return
+	}
+	ap, err := pm.createAudioPlayer(nextEntry)
+	if err != nil {
+		// playback will simply stop and advance the usual way at the end of the current track
+		log.Printf("could not preload: %s, error: %v", nextEntry.URI, err)
+		return
+	}
+	if !pm.audioPlayer.IsPaused() {
+		ap.Play()
+	}
+	pm.sequencer.SetNext(ap, pm.getCrossfadeDuration(ap, nextEntry))
+	pm.preloadedPlayer, pm.preloadedEntryId = ap, nextEntry.ID
 }
 
-func (pm *PlaybackManager) resampleTrackIfNeeded() {
-	newSampleRate := pm.audioPlayer.Format.SampleRate
-	if int32(newSampleRate) != baseSampleRate {
-		log.Printf("resampling %s from %d to %d", pm.GetCurrentTrackName(), int32(newSampleRate), baseSampleRate)
-		resampled := beep.Resample(4, newSampleRate, beep.SampleRate(baseSampleRate), pm.audioPlayer.Ctrl)
-		speaker.Play(resampled)
+// reloadNextTrack preloads the next track from its beginning even when it is preloaded already,
+// for changes of the current track after which it may have started fading in too early
+func (pm *PlaybackManager) reloadNextTrack() {
+	if pm.discardPreloadedTrack() {
+		pm.preloadNextTrack()
+	}
+}
+
+// returns the entry which is played after the current one once it ends, found is false when playback stops instead
+func (pm *PlaybackManager) getEntryToPreload() (entry QueueEntry, found bool) {
+	if pm.preloadedPlayer != nil && pm.options.Random && !pm.options.Single && pm.getPriorityQueuePosition() == -1 {
+		// any unplayed entry may come next in random order, so the preloaded one is kept while it is still unplayed
+		position, err := pm.findQueuePositionById(pm.preloadedEntryId)
+		if err == nil && position != pm.QueuePosition && !pm.randomRoundPlayedIds[pm.preloadedEntryId] && !pm.shouldSleepAfterCurrent(position) {
+			return pm.playbackQueue[position], true
+		}
+	}
+	nextPosition, shouldStop := pm.getNextQueuePosition(true)
+	if shouldStop || nextPosition >= len(pm.playbackQueue) {
+		return QueueEntry{}, false
+	}
+	nextEntry := pm.playbackQueue[nextPosition]
+	if pm.options.Consume && nextEntry.ID == pm.getCurrentEntryId() {
+		// the current entry is removed once it finishes, so there is nothing to repeat
+		return QueueEntry{}, false
+	}
+	return nextEntry, true
+}
+
+// returns how long the current track fades into the next one, 0 for a gapless transition
+func (pm *PlaybackManager) getCrossfadeDuration(nextPlayer *audioplayer.AudioPlayer, nextEntry QueueEntry) time.Duration {
+	crossfade := pm.options.Crossfade
+	if crossfade <= 0 {
+		return 0
+	}
+	// fading over a large part of a short track would leave hardly anything of it
+	minimumTrackDuration := minimumCrossfadeTrackRatio * crossfade
+	if pm.audioPlayer.GetDuration() < minimumTrackDuration || nextPlayer.GetDuration() < minimumTrackDuration {
+		return 0
+	}
+	if pm.options.SkipCrossfadeWithinAlbum {
+		currentAlbum := getAlbum(pm.playbackQueue[pm.QueuePosition])
+		if currentAlbum != "" && currentAlbum == getAlbum(nextEntry) {
+			return 0
+		}
+	}
+	return crossfade
+}
+
+// discards the preloaded track, unless the sequencer has switched to it already. It is being streamed as the current track then,
+// and kept for the finish callback of the track before it, which adopts it. Reports whether there is no preloaded track anymore
+func (pm *PlaybackManager) discardPreloadedTrack() bool {
+	if pm.preloadedPlayer == nil {
+		return true
+	}
+	if pm.sequencer.SetNext(nil, 0) {
+		return false
+	}
+	pm.closePreloadedTrack()
+	return true
+}
+
+// closes the preloaded track, which must not be streamed by the sequencer anymore
+func (pm *PlaybackManager) closePreloadedTrack() {
+	err := pm.preloadedPlayer.Close()
+	if err != nil {
+		log.Printf("error while closing preloaded track: %v", err)
+	}
+	pm.preloadedPlayer = nil
+}
+
+// continueWithPreloadedTrack catches up with the sequencer, which has already started streaming the preloaded track
+// right after the last sample of the current one
+func (pm *PlaybackManager) continueWithPreloadedTrack() error {
+	finishedPlayer := pm.audioPlayer
+	nextPlayer, nextEntryId := pm.preloadedPlayer, pm.preloadedEntryId
+	pm.publishTrackFinished(pm.playbackQueue[pm.QueuePosition], finishedPlayer.GetDuration(), finishedPlayer.GetDuration(), true)
+	pm.audioPlayer, pm.preloadedPlayer = nextPlayer, nil
+	err := finishedPlayer.Close()
+	if err != nil {
+		log.Printf("error while closing finished track: %v", err)
+	}
+
+	consumed := pm.options.Consume && pm.consumeCurrentEntry()
+	pm.followCurrentEntry(nextEntryId)
+	log.Printf("playing: %s", pm.getCurrentTrackName())
+	pm.randomRoundPlayedIds[nextEntryId] = true
+	pm.currentTrackStarted = true
+	pm.resetCurrentEntryPriority()
+	pm.publishTrackEvent(EventTrackStarted)
+	pm.notifier.Notify(idle.SubsystemPlayer)
+	if consumed {
+		pm.queueModified()
 	} else {
-		speaker.Play(pm.audioPlayer.Ctrl)
+		pm.preloadNextTrack()
+	}
+	return nil
+}
+
+// returns the album of a track, empty when it is unknown
+func getAlbum(entry QueueEntry) string {
+	if entry.Metadata == nil {
+		return ""
 	}
+	return strings.Join(entry.Metadata.Album, ";")
+}
+
+// removes the entry at QueuePosition, which then points at the entry following it. It reports whether anything was removed
+func (pm *PlaybackManager) consumeCurrentEntry() bool {
+	if pm.QueuePosition >= len(pm.playbackQueue) {
+		return false
+	}
+	pm.playbackQueue = append(pm.playbackQueue[:pm.QueuePosition], pm.playbackQueue[pm.QueuePosition+1:]...)
+	return true
 }
 
 func (pm *PlaybackManager) moveQueuePosition(delta int) error {
@@ -195,15 +496,6 @@ func (pm *PlaybackManager) isQueuePaused() bool {
 	return pm.audioPlayer != nil && pm.audioPlayer.IsPaused()
 }
 
-func (pm *PlaybackManager) addAudioFileToQueue(filePath string) error {
-	err := audioplayer.IsFileSupported(filePath)
-	if err != nil {
-		return err
-	}
-	pm.playbackQueue = append(pm.playbackQueue, filePath)
-	return nil
-}
-
 func (pm *PlaybackManager) play() error {
 	if pm.QueuePosition < 0 {
 		panic(fmt.Sprintf("Queue position: %d is invalid", pm.QueuePosition))
@@ -220,8 +512,22 @@ func (pm *PlaybackManager) play() error {
 	if !pm.isQueuePaused() {
 		return fmt.Errorf("queue is already playing")
 	}
-	log.Printf("playing: %s", pm.GetCurrentTrackName())
+	log.Printf("playing: %s", pm.getCurrentTrackName())
+	pm.randomRoundPlayedIds[pm.getCurrentEntryId()] = true
 	pm.audioPlayer.Play()
+	if pm.preloadedPlayer == nil {
+		pm.preloadNextTrack()
+	} else {
+		pm.preloadedPlayer.Play()
+	}
+	if pm.currentTrackStarted {
+		pm.publishTrackEvent(EventResumed)
+	} else {
+		pm.currentTrackStarted = true
+		pm.resetCurrentEntryPriority()
+		pm.publishTrackEvent(EventTrackStarted)
+	}
+	pm.notifier.Notify(idle.SubsystemPlayer)
 	return nil
 }
 func (pm *PlaybackManager) pause() error {
@@ -232,31 +538,82 @@ func (pm *PlaybackManager) pause() error {
 		return fmt.Errorf("queue is already paused")
 	}
 	pm.audioPlayer.Pause()
+	if pm.preloadedPlayer != nil {
+		// the preloaded track is already audible while crossfading into it
+		pm.preloadedPlayer.Pause()
+	}
+	pm.publishTrackEvent(EventPaused)
+	pm.notifier.Notify(idle.SubsystemPlayer)
 	return nil
 }
 
 func (pm *PlaybackManager) stop() error {
+	return pm.stopTrack(false)
+}
+
+// stops and unloads the current track, completed tells whether it is stopped because it played until its end
+func (pm *PlaybackManager) stopTrack(completed bool) error {
 	if pm.QueuePosition == len(pm.playbackQueue) || pm.audioPlayer == nil {
 		return fmt.Errorf("no active audio file in queue")
 	}
+	elapsed := pm.audioPlayer.GetCurrentPosition()
+	if completed {
+		elapsed = pm.audioPlayer.GetDuration()
+	}
+	pm.publishTrackFinished(pm.playbackQueue[pm.QueuePosition], elapsed, pm.audioPlayer.GetDuration(), completed)
+	pm.sequencer.Clear()
+	if pm.preloadedPlayer != nil {
+		// the sequencer may have switched to it already, it can only be closed once it was cleared
+		pm.closePreloadedTrack()
+	}
 	err := pm.audioPlayer.Close()
 	if err != nil {
 		return err
 	}
 	pm.audioPlayer = nil
+	pm.notifier.Notify(idle.SubsystemPlayer)
 	return nil
 }
 
 func (pm *PlaybackManager) next() error {
+	return pm.advance(false)
+}
+
+// advance moves on from the current track to the one chosen by the playback options.
+// trackFinished is set when called because the current track played until its end
+func (pm *PlaybackManager) advance(trackFinished bool) error {
 	initiallyQueuePaused := pm.isQueuePaused()
-	err := pm.moveQueuePosition(1)
+	finishedEntryId := pm.getCurrentEntryId()
+	nextPosition, shouldStop := pm.getNextQueuePosition(trackFinished)
+	nextEntryId := -1
+	if nextPosition < len(pm.playbackQueue) {
+		nextEntryId = pm.playbackQueue[nextPosition].ID
+	}
+
+	err := pm.stopTrack(trackFinished)
 	if err != nil {
-		return err
+		return fmt.Errorf("error while stopping current playback: %s", err.Error())
+	}
+	if trackFinished && shouldStop {
+		pm.sleepAfterCurrentDone()
+	}
+	if pm.options.Consume && pm.consumeCurrentEntry() {
+		pm.queueModified()
+		if nextEntryId == finishedEntryId {
+			// single mode would repeat the track which was just consumed
+			nextEntryId, shouldStop = -1, true
+		}
+	}
+	pm.followCurrentEntry(nextEntryId)
+	if nextEntryId == -1 && !shouldStop {
+		pm.QueuePosition = len(pm.playbackQueue)
+	}
+
+	if shouldStop {
+		return nil
 	}
 	if pm.QueuePosition == len(pm.playbackQueue) {
-		go func() {
-			pm.QueuePlaybackFinished <- true
-		}()
+		pm.publishQueueEnded(trackFinished)
 		log.Print("reached the end of playback queue")
 		return nil
 	}
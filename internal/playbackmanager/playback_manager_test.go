@@ -14,6 +14,8 @@ func TestPlayPauseStop(t *testing.T) {
 		"../../music/sample-12s.mp3",
 	}
 	playbackManager := CreatePlaybackManager()
+	subscription := playbackManager.Subscribe(0)
+	defer subscription.Unsubscribe()
 	err := playbackManager.AddAudioFilesToQueue(musicFiles...)
 	if err != nil {
 		t.Error(err)
@@ -34,7 +36,7 @@ func TestPlayPauseStop(t *testing.T) {
 		println("playing")
 		_ = playbackManager.Play()
 	}
-	<-playbackManager.QueuePlaybackFinished
+	waitForEvent(t, subscription, EventQueueEnded, time.Minute)
 }
 func TestAutoNext(t *testing.T) {
 	musicFiles := []string{
@@ -44,12 +46,14 @@ func TestAutoNext(t *testing.T) {
 		"../../music/sample-96kHz24bit.flac",
 	}
 	playbackManager := CreatePlaybackManager()
+	subscription := playbackManager.Subscribe(0)
+	defer subscription.Unsubscribe()
 	err := playbackManager.AddAudioFilesToQueue(musicFiles...)
 	if err != nil {
 		t.Error(err)
 	}
 	_ = playbackManager.Play()
-	<-playbackManager.QueuePlaybackFinished
+	waitForEvent(t, subscription, EventQueueEnded, time.Minute)
 }
 func TestNextPrevious(t *testing.T) {
 	musicFiles := []string{
@@ -60,6 +64,8 @@ func TestNextPrevious(t *testing.T) {
 		"../../music/sample-12s.mp3",
 	}
 	playbackManager := CreatePlaybackManager()
+	subscription := playbackManager.Subscribe(0)
+	defer subscription.Unsubscribe()
 	err := playbackManager.AddAudioFilesToQueue(musicFiles...)
 	if err != nil {
 		t.Error(err)
@@ -77,5 +83,71 @@ func TestNextPrevious(t *testing.T) {
 	println("skipping 2 tracks")
 	_ = playbackManager.Next()
 	_ = playbackManager.Next()
-	<-playbackManager.QueuePlaybackFinished
+	waitForEvent(t, subscription, EventQueueEnded, time.Minute)
+}
+func TestGetStatus(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	err := playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := playbackManager.GetStatus()
+	if status.State != PlaybackStateStopped || status.QueueLength != 1 {
+		t.Errorf("unexpected status before playback: %+v", status)
+	}
+	_ = playbackManager.Play()
+	status = playbackManager.GetStatus()
+	if status.State != PlaybackStatePlaying || status.CurrentFilePath != "../../music/sample-3s.mp3" {
+		t.Errorf("unexpected status during playback: %+v", status)
+	}
+	if status.Duration <= 0 || status.Elapsed > status.Duration {
+		t.Errorf("invalid elapsed time or duration: %v/%v", status.Elapsed, status.Duration)
+	}
+	if status.SampleRate == 0 || status.BitDepth == 0 || status.Channels == 0 {
+		t.Errorf("audio format missing from status: %+v", status)
+	}
+	_ = playbackManager.Stop()
+}
+
+func TestPreloadKeptWhileNext(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3"), t)
+	checkError(playbackManager.Play(), t)
+	defer playbackManager.Stop()
+	preloadedPlayer := playbackManager.preloadedPlayer
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-12s.mp3"), t)
+	if playbackManager.preloadedPlayer != preloadedPlayer {
+		t.Error("expected the preloaded track to be kept while it still comes next")
+	}
+	checkError(playbackManager.MoveInQueue(2, 3, 1), t)
+	if playbackManager.preloadedEntryId != playbackManager.GetQueue()[1].ID || playbackManager.preloadedPlayer == preloadedPlayer {
+		t.Errorf("expected the entry moved after the current one to be preloaded, got id: %d", playbackManager.preloadedEntryId)
+	}
+}
+
+func TestGaplessTransition(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3"), t)
+	checkError(playbackManager.Play(), t)
+	queue := playbackManager.GetQueue()
+	if playbackManager.preloadedPlayer == nil || playbackManager.preloadedEntryId != queue[1].ID {
+		t.Fatalf("expected the second entry to be preloaded, got id: %d", playbackManager.preloadedEntryId)
+	}
+
+	// skip to the last second of the first track and wait for the boundary
+	checkError(playbackManager.Seek(time.Second*6), t)
+	deadline := time.Now().Add(time.Second * 5)
+	for playbackManager.GetStatus().QueuePosition != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("playback did not continue with the preloaded track")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	if status := playbackManager.GetStatus(); status.State != PlaybackStatePlaying || status.CurrentId != queue[1].ID {
+		t.Errorf("unexpected status after the boundary: %+v", status)
+	}
+	if playbackManager.preloadedPlayer != nil {
+		t.Error("nothing should be preloaded after the last track")
+	}
+	_ = playbackManager.Stop()
 }
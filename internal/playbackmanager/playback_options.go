@@ -0,0 +1,161 @@
+package playbackmanager
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+// PlaybackOptions control which track is played once the current one is over
+type PlaybackOptions struct {
+	Repeat  bool // start over from the beginning of the queue after the last track
+	Random  bool // play the queue in a shuffled order, every entry once per round
+	Single  bool // stop after the current track, or loop it when Repeat is set as well
+	Consume bool // remove tracks from the queue once they were played
+
+	Crossfade                time.Duration // overlap between consecutive tracks, 0 for gapless playback
+	SkipCrossfadeWithinAlbum bool          // play consecutive tracks of the same album gaplessly even when crossfading
+}
+
+func (pm *PlaybackManager) GetOptions() PlaybackOptions {
+	pm.playbackQueueLock.Lock()
+	defer pm.playbackQueueLock.Unlock()
+	return pm.options
+}
+
+func (pm *PlaybackManager) SetRepeat(repeat bool) {
+	pm.setOptions(func(options *PlaybackOptions) { options.Repeat = repeat })
+}
+
+func (pm *PlaybackManager) SetRandom(random bool) {
+	pm.setOptions(func(options *PlaybackOptions) {
+		if random && !options.Random {
+			pm.startNewRandomRound()
+		}
+		options.Random = random
+	})
+}
+
+func (pm *PlaybackManager) SetSingle(single bool) {
+	pm.setOptions(func(options *PlaybackOptions) { options.Single = single })
+}
+
+func (pm *PlaybackManager) SetConsume(consume bool) {
+	pm.setOptions(func(options *PlaybackOptions) { options.Consume = consume })
+}
+
+func (pm *PlaybackManager) SetCrossfade(crossfade time.Duration) error {
+	if crossfade < 0 {
+		return fmt.Errorf("invalid crossfade duration: %v", crossfade)
+	}
+	pm.setOptions(func(options *PlaybackOptions) { options.Crossfade = crossfade })
+	return nil
+}
+
+func (pm *PlaybackManager) SetSkipCrossfadeWithinAlbum(skip bool) {
+	pm.setOptions(func(options *PlaybackOptions) { options.SkipCrossfadeWithinAlbum = skip })
+}
+
+func (pm *PlaybackManager) setOptions(update func(options *PlaybackOptions)) {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	update(&pm.options)
+	pm.preloadNextTrack()
+	pm.notifier.Notify(idle.SubsystemOptions)
+}
+
+// getNextQueuePosition decides which entry to play after the current one according to the playback options.
+// trackFinished is set when the current track played until its end, as opposed to the user skipping it.
+// It returns len(playbackQueue) when the end of the queue is reached, and shouldStop when playback should
+// stop at the returned position instead of continuing with it
+func (pm *PlaybackManager) getNextQueuePosition(trackFinished bool) (position int, shouldStop bool) {
+	position, shouldStop = pm.getNextQueuePositionByOptions(trackFinished)
+	if trackFinished && !shouldStop && pm.shouldSleepAfterCurrent(position) {
+		// the sleep timer stops playback at the entry which would have been played next
+		return position, true
+	}
+	return position, shouldStop
+}
+
+func (pm *PlaybackManager) getNextQueuePositionByOptions(trackFinished bool) (position int, shouldStop bool) {
+	queueLength := len(pm.playbackQueue)
+	if trackFinished && pm.options.Single {
+		if pm.options.Repeat {
+			return pm.QueuePosition, false
+		}
+		return pm.QueuePosition, true
+	}
+	if position := pm.getPriorityQueuePosition(); position != -1 {
+		return position, false
+	}
+	if pm.options.Random {
+		return pm.getRandomUnplayedQueuePosition(), false
+	}
+	position = pm.QueuePosition + 1
+	if position >= queueLength && pm.options.Repeat && queueLength > 0 {
+		position = 0
+	}
+	return min(position, queueLength), false
+}
+
+// picks an entry which has not been played yet in this random round. Once every entry was played,
+// a new round is started if repeat is on, otherwise the end of the queue is reached
+func (pm *PlaybackManager) getRandomUnplayedQueuePosition() int {
+	unplayedPositions := pm.getRandomUnplayedQueuePositions()
+	if len(unplayedPositions) == 0 && pm.options.Repeat {
+		pm.startNewRandomRound()
+		unplayedPositions = pm.getRandomUnplayedQueuePositions()
+		if len(unplayedPositions) == 0 {
+			// the current entry is the only one in the queue
+			return pm.QueuePosition
+		}
+	}
+	if len(unplayedPositions) == 0 {
+		return len(pm.playbackQueue)
+	}
+	return unplayedPositions[rand.Intn(len(unplayedPositions))]
+}
+
+func (pm *PlaybackManager) getRandomUnplayedQueuePositions() []int {
+	unplayedPositions := []int{}
+	for position, entry := range pm.playbackQueue {
+		if !pm.randomRoundPlayedIds[entry.ID] && position != pm.QueuePosition {
+			unplayedPositions = append(unplayedPositions, position)
+		}
+	}
+	return unplayedPositions
+}
+
+// GetUnplayedCount returns how many entries other than the current one are still to be played before the queue ends:
+// the ones after the current entry, or the ones not played yet in this round of random playback, along with every
+// entry which has a priority. Repeat and single playback are not taken into account
+func (pm *PlaybackManager) GetUnplayedCount() int {
+	pm.playbackQueueLock.Lock()
+	defer pm.playbackQueueLock.Unlock()
+	unplayed := 0
+	for position, entry := range pm.playbackQueue {
+		if position == pm.QueuePosition {
+			continue
+		}
+		upcoming := position > pm.QueuePosition
+		if pm.options.Random {
+			upcoming = !pm.randomRoundPlayedIds[entry.ID]
+		}
+		if upcoming || entry.Priority > 0 {
+			unplayed++
+		}
+	}
+	return unplayed
+}
+
+// forgets which entries were played in the random order, except the current one
+func (pm *PlaybackManager) startNewRandomRound() {
+	pm.randomRoundPlayedIds = map[int]bool{}
+	if currentEntryId := pm.getCurrentEntryId(); currentEntryId != -1 {
+		pm.randomRoundPlayedIds[currentEntryId] = true
+	}
+}
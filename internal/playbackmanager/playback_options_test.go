@@ -0,0 +1,116 @@
+package playbackmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+func TestGetNextQueuePosition(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3", "../../music/sample-12s.mp3"), t)
+	playbackManager.QueuePosition = 2
+
+	testCases := []struct {
+		options       PlaybackOptions
+		trackFinished bool
+		position      int
+		shouldStop    bool
+	}{
+		{PlaybackOptions{}, true, 3, false},
+		{PlaybackOptions{Repeat: true}, true, 0, false},
+		{PlaybackOptions{Single: true}, true, 2, true},
+		{PlaybackOptions{Single: true}, false, 3, false},
+		{PlaybackOptions{Single: true, Repeat: true}, true, 2, false},
+	}
+	for _, testCase := range testCases {
+		playbackManager.options = testCase.options
+		position, shouldStop := playbackManager.getNextQueuePosition(testCase.trackFinished)
+		if position != testCase.position || shouldStop != testCase.shouldStop {
+			t.Errorf("options: %+v, finished: %v, expected: (%d, %v), got: (%d, %v)",
+				testCase.options, testCase.trackFinished, testCase.position, testCase.shouldStop, position, shouldStop)
+		}
+	}
+}
+
+func TestGetUnplayedCount(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3", "../../music/sample-12s.mp3", "../../music/sample-15s.mp3"), t)
+	playbackManager.QueuePosition = 2
+	if unplayed := playbackManager.GetUnplayedCount(); unplayed != 1 {
+		t.Errorf("expected only the entry after the current one, got: %d", unplayed)
+	}
+	checkError(playbackManager.SetPriority(1, 0, 1), t)
+	if unplayed := playbackManager.GetUnplayedCount(); unplayed != 2 {
+		t.Errorf("expected the prioritized entry to count as well, got: %d", unplayed)
+	}
+	playbackManager.options.Random = true
+	playbackManager.randomRoundPlayedIds = map[int]bool{playbackManager.playbackQueue[1].ID: true}
+	if unplayed := playbackManager.GetUnplayedCount(); unplayed != 2 {
+		t.Errorf("expected the entries not played in this random round, got: %d", unplayed)
+	}
+}
+
+func TestRandomPlaysEveryEntryOnce(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3", "../../music/sample-12s.mp3"), t)
+	playbackManager.SetRandom(true)
+
+	visited := map[int]bool{playbackManager.QueuePosition: true}
+	playbackManager.randomRoundPlayedIds[playbackManager.getCurrentEntryId()] = true
+	for i := 0; i < 2; i++ {
+		position, _ := playbackManager.getNextQueuePosition(false)
+		if visited[position] || position >= 3 {
+			t.Fatalf("random order repeated or ended early at position %d, visited: %v", position, visited)
+		}
+		visited[position] = true
+		playbackManager.QueuePosition = position
+		playbackManager.randomRoundPlayedIds[playbackManager.getCurrentEntryId()] = true
+	}
+	if position, _ := playbackManager.getNextQueuePosition(false); position != 3 {
+		t.Errorf("expected the end of the queue once every entry was played, got: %d", position)
+	}
+
+	playbackManager.SetRepeat(true)
+	if position, _ := playbackManager.getNextQueuePosition(false); position >= 3 || position == playbackManager.QueuePosition {
+		t.Errorf("expected a new random round with repeat on, got: %d", position)
+	}
+}
+
+func TestGetCrossfadeDuration(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-12s.mp3"), t)
+	checkError(playbackManager.Play(), t)
+	checkError(playbackManager.Pause(), t)
+	defer playbackManager.Stop()
+
+	testCases := []struct {
+		crossfade time.Duration
+		skipAlbum bool
+		sameAlbum bool
+		expected  time.Duration
+	}{
+		{0, false, false, 0},
+		{time.Second * 2, false, false, time.Second * 2},
+		{time.Second * 5, false, false, 0}, // the 6 second long current track is too short
+		{time.Second * 2, true, false, time.Second * 2},
+		{time.Second * 2, true, true, 0},
+		{time.Second * 2, false, true, time.Second * 2},
+	}
+	for _, testCase := range testCases {
+		checkError(playbackManager.SetCrossfade(testCase.crossfade), t)
+		playbackManager.SetSkipCrossfadeWithinAlbum(testCase.skipAlbum)
+		for i := range playbackManager.playbackQueue {
+			album := playbackManager.playbackQueue[i].URI
+			if testCase.sameAlbum {
+				album = "album"
+			}
+			playbackManager.playbackQueue[i].Metadata = &database.AudioFileMetadata{Album: []string{album}}
+		}
+		crossfade := playbackManager.getCrossfadeDuration(playbackManager.preloadedPlayer, playbackManager.GetQueue()[1])
+		if crossfade != testCase.expected {
+			t.Errorf("%+v: expected %v, got %v", testCase, testCase.expected, crossfade)
+		}
+	}
+}
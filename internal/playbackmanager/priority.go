@@ -0,0 +1,120 @@
+package playbackmanager
+
+import (
+	"fmt"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+const MaximumPriority = 255
+
+// SetPriority sets the priority of the entries in the range [start, end). Entries with a priority above 0 are played
+// before the rest of the queue, the highest priority first, regardless of their position and of random playback
+func (pm *PlaybackManager) SetPriority(priority, start, end int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if err := validatePriority(priority); err != nil {
+		return err
+	}
+	if start < 0 || end > len(pm.playbackQueue) || start >= end {
+		return fmt.Errorf("invalid queue range: %d:%d", start, end)
+	}
+	for position := start; position < end; position++ {
+		pm.playbackQueue[position].Priority = priority
+	}
+	pm.queueModified()
+	return nil
+}
+
+// SetPriorityIds sets the priority of the entries with the given IDs, nothing is changed if any of them does not exist
+func (pm *PlaybackManager) SetPriorityIds(priority int, ids ...int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if err := validatePriority(priority); err != nil {
+		return err
+	}
+	positions := []int{}
+	for _, id := range ids {
+		position, err := pm.findQueuePositionById(id)
+		if err != nil {
+			return err
+		}
+		positions = append(positions, position)
+	}
+	for _, position := range positions {
+		pm.playbackQueue[position].Priority = priority
+	}
+	pm.queueModified()
+	return nil
+}
+
+// PlayNext gives the entry a higher priority than every other one, so it is played right after the current track.
+// Once the maximum priority is reached, the other prioritized entries are lowered by one to make room, keeping at least priority 1
+func (pm *PlaybackManager) PlayNext(id int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	position, err := pm.findQueuePositionById(id)
+	if err != nil {
+		return err
+	}
+	if position == pm.QueuePosition {
+		return fmt.Errorf("id %d is the current track", id)
+	}
+	highestPriority := 0
+	for _, entry := range pm.playbackQueue {
+		if entry.ID != id {
+			highestPriority = max(highestPriority, entry.Priority)
+		}
+	}
+	if highestPriority == MaximumPriority {
+		for i := range pm.playbackQueue {
+			if i != position && pm.playbackQueue[i].Priority > 1 {
+				pm.playbackQueue[i].Priority--
+			}
+		}
+	}
+	pm.playbackQueue[position].Priority = min(highestPriority+1, MaximumPriority)
+	pm.queueModified()
+	return nil
+}
+
+// returns the position of the entry with the highest priority other than the current one, -1 when no entry has a priority.
+// Between entries of the same priority, the one coming first after the current entry wins
+func (pm *PlaybackManager) getPriorityQueuePosition() int {
+	queueLength := len(pm.playbackQueue)
+	first := min(pm.QueuePosition+1, queueLength)
+	priorityPosition, highestPriority := -1, 0
+	for i := 0; i < queueLength; i++ {
+		position := (first + i) % queueLength
+		if position == pm.QueuePosition {
+			continue
+		}
+		if priority := pm.playbackQueue[position].Priority; priority > highestPriority {
+			priorityPosition, highestPriority = position, priority
+		}
+	}
+	return priorityPosition
+}
+
+// an entry is played only once for its priority, which is reset as soon as it starts playing
+func (pm *PlaybackManager) resetCurrentEntryPriority() {
+	if pm.QueuePosition >= len(pm.playbackQueue) || pm.playbackQueue[pm.QueuePosition].Priority == 0 {
+		return
+	}
+	pm.playbackQueue[pm.QueuePosition].Priority = 0
+	pm.queueVersion++
+	pm.notifier.Notify(idle.SubsystemPlaylist)
+}
+
+func validatePriority(priority int) error {
+	if priority < 0 || priority > MaximumPriority {
+		return fmt.Errorf("invalid priority: %d, expected 0 to %d", priority, MaximumPriority)
+	}
+	return nil
+}
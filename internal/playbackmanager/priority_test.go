@@ -0,0 +1,51 @@
+package playbackmanager
+
+import "testing"
+
+func TestPriority(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3", "../../music/sample-12s.mp3", "../../music/sample-15s.mp3"), t)
+	queue := playbackManager.GetQueue()
+
+	checkError(playbackManager.SetPriority(10, 2, 4), t)
+	if position, _ := playbackManager.getNextQueuePosition(false); position != 2 {
+		t.Errorf("expected the first entry of the same priority after the current one, got: %d", position)
+	}
+	checkError(playbackManager.SetPriorityIds(20, queue[3].ID), t)
+	if position, _ := playbackManager.getNextQueuePosition(false); position != 3 {
+		t.Errorf("expected the entry with the highest priority, got: %d", position)
+	}
+	checkError(playbackManager.PlayNext(queue[1].ID), t)
+	if priority := playbackManager.GetQueue()[1].Priority; priority != 21 {
+		t.Errorf("expected play next to outrank every other entry, got priority: %d", priority)
+	}
+
+	playbackManager.SetRandom(true)
+	checkError(playbackManager.Play(), t)
+	for _, expected := range []int{1, 3, 2} {
+		checkError(playbackManager.Next(), t)
+		if playbackManager.QueuePosition != expected {
+			t.Fatalf("expected position %d to play next, got: %d", expected, playbackManager.QueuePosition)
+		}
+		if priority := playbackManager.GetQueue()[expected].Priority; priority != 0 {
+			t.Errorf("expected the priority to be reset once the entry was played, got: %d", priority)
+		}
+	}
+	checkError(playbackManager.Stop(), t)
+
+	if err := playbackManager.SetPriority(MaximumPriority+1, 0, 1); err == nil {
+		t.Errorf("expected an error for a priority out of range")
+	}
+
+	// at the maximum priority, the others make room so that the entry still outranks them
+	checkError(playbackManager.SetPriority(MaximumPriority, 0, 2), t)
+	checkError(playbackManager.PlayNext(queue[3].ID), t)
+	if position, _ := playbackManager.getNextQueuePosition(false); position != 3 || playbackManager.GetQueue()[0].Priority != MaximumPriority-1 {
+		t.Errorf("expected play next to outrank entries of the maximum priority, got position: %d", position)
+	}
+	checkError(playbackManager.SetPriority(0, 0, 4), t)
+
+	if err := playbackManager.SetPriorityIds(1, queue[0].ID, -1); err == nil || playbackManager.GetQueue()[0].Priority != 0 {
+		t.Errorf("expected nothing to change when an id does not exist, got error: %v", err)
+	}
+}
@@ -0,0 +1,405 @@
+package playbackmanager
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+// returns a copy of the entries in the playback queue
+func (pm *PlaybackManager) GetQueue() []QueueEntry {
+	pm.playbackQueueLock.Lock()
+	defer pm.playbackQueueLock.Unlock()
+	queue := make([]QueueEntry, len(pm.playbackQueue))
+	copy(queue, pm.playbackQueue)
+	return queue
+}
+
+// AddError reports the URIs which could not be added to the queue, the others were added anyway
+type AddError struct {
+	Rejected []string
+}
+
+func (err *AddError) Error() string {
+	return fmt.Sprintf("could not add: %s", strings.Join(err.Rejected, ","))
+}
+
+// AddAudioFilesToQueue appends local files or stream URLs to the queue.
+// Files which could not be added are reported in an *AddError, the rest are still added
+func (pm *PlaybackManager) AddAudioFilesToQueue(uris ...string) error {
+	return pm.AddEntriesToQueue(newQueueEntries(uris)...)
+}
+
+// AddEntriesToQueue is the same as AddAudioFilesToQueue for entries created up front, like the ones whose metadata is known already
+func (pm *PlaybackManager) AddEntriesToQueue(entries ...QueueEntry) error {
+	return pm.AddEntriesToQueueInStep(QueueStep{}, entries...)
+}
+
+// AddEntriesToQueueInStep appends entries as part of a step started with NewQueueStep, so that several additions,
+// like the batches of a long running add, are undone together
+func (pm *PlaybackManager) AddEntriesToQueueInStep(step QueueStep, entries ...QueueEntry) error {
+	entries, rejected := pm.prepareEntries(entries)
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueStep(pm.getQueueState(), step)
+	pm.insertEntriesToQueue(len(pm.playbackQueue), entries)
+	return newAddError(rejected)
+}
+
+// AddAutomaticAudioFilesToQueue appends files which the user did not ask for, like the ones chosen by the auto DJ.
+// The addition can not be undone, and undoing other modifications leaves these entries in the queue
+func (pm *PlaybackManager) AddAutomaticAudioFilesToQueue(uris ...string) error {
+	entries, rejected := pm.prepareEntries(newQueueEntries(uris))
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	pm.insertEntriesToQueue(len(pm.playbackQueue), entries)
+	return newAddError(rejected)
+}
+
+// InsertAudioFilesToQueue inserts local files or stream URLs at the given queue position and returns the IDs of the new entries.
+// Files which could not be added are reported in the error, the rest are still inserted
+func (pm *PlaybackManager) InsertAudioFilesToQueue(position int, uris ...string) ([]int, error) {
+	return pm.InsertEntriesToQueue(position, newQueueEntries(uris)...)
+}
+
+// InsertEntriesToQueue is the same as InsertAudioFilesToQueue for entries created up front, like virtual tracks.
+// The entries get new IDs and their metadata is looked up if it is missing
+func (pm *PlaybackManager) InsertEntriesToQueue(position int, entries ...QueueEntry) ([]int, error) {
+	entries, rejected := pm.prepareEntries(entries)
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	if position < 0 || position > len(pm.playbackQueue) {
+		return nil, fmt.Errorf("invalid queue position: %d", position)
+	}
+	return pm.insertEntriesToQueue(position, entries), newAddError(rejected)
+}
+
+// DeleteFromQueue removes the entries in the range [start, end). If the current track is removed,
+// playback continues with the entry that takes its place
+func (pm *PlaybackManager) DeleteFromQueue(start, end int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	return pm.deleteFromQueue(start, end)
+}
+
+func (pm *PlaybackManager) DeleteIdFromQueue(id int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	position, err := pm.findQueuePositionById(id)
+	if err != nil {
+		return err
+	}
+	return pm.deleteFromQueue(position, position+1)
+}
+
+// MoveInQueue moves the entries in the range [start, end) so that the first of them ends up at position to
+func (pm *PlaybackManager) MoveInQueue(start, end, to int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	return pm.moveInQueue(start, end, to)
+}
+
+func (pm *PlaybackManager) MoveIdInQueue(id, to int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	position, err := pm.findQueuePositionById(id)
+	if err != nil {
+		return err
+	}
+	return pm.moveInQueue(position, position+1, to)
+}
+
+func (pm *PlaybackManager) SwapInQueue(position1, position2 int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	return pm.swapInQueue(position1, position2)
+}
+
+func (pm *PlaybackManager) SwapIdsInQueue(id1, id2 int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	position1, err := pm.findQueuePositionById(id1)
+	if err != nil {
+		return err
+	}
+	position2, err := pm.findQueuePositionById(id2)
+	if err != nil {
+		return err
+	}
+	return pm.swapInQueue(position1, position2)
+}
+
+// ShuffleQueue shuffles the entries in the range [start, end), the current track keeps playing wherever it ends up
+func (pm *PlaybackManager) ShuffleQueue(start, end int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	if start < 0 || end > len(pm.playbackQueue) || start >= end {
+		return fmt.Errorf("invalid queue range: %d:%d", start, end)
+	}
+	currentEntryId := pm.getCurrentEntryId()
+	entries := pm.playbackQueue[start:end]
+	rand.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+	pm.followCurrentEntry(currentEntryId)
+	pm.queueModified()
+	return nil
+}
+
+// ClearQueue stops playback and removes every entry from the queue
+func (pm *PlaybackManager) ClearQueue() error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	defer pm.rememberQueueState(pm.getQueueState())
+	if pm.audioPlayer != nil {
+		err := pm.stop()
+		if err != nil {
+			return err
+		}
+	}
+	pm.playbackQueue = []QueueEntry{}
+	pm.QueuePosition = 0
+	pm.randomRoundPlayedIds = map[int]bool{}
+	pm.queueModified()
+	return nil
+}
+
+func (pm *PlaybackManager) PlayId(id int) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	position, err := pm.findQueuePositionById(id)
+	if err != nil {
+		return err
+	}
+	return pm.playQueuePosition(position)
+}
+
+// SetEntryRange turns the entry into a virtual track playing only the given range of its file, nil plays the whole file again.
+// The range of the current track can not be changed while it is loaded
+func (pm *PlaybackManager) SetEntryRange(id int, trackRange *TrackRange) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	position, err := pm.findQueuePositionById(id)
+	if err != nil {
+		return err
+	}
+	entry := pm.playbackQueue[position]
+	if !entry.IsLocal() {
+		return fmt.Errorf("only local files can be played partially: %s", entry.URI)
+	}
+	if position == pm.QueuePosition && pm.audioPlayer != nil {
+		return fmt.Errorf("can not change the range of the current track")
+	}
+	if trackRange == nil {
+		entry.Kind, entry.Range = SourceKindFile, nil
+	} else {
+		if err := trackRange.validate(); err != nil {
+			return err
+		}
+		rangeCopy := *trackRange
+		entry.Kind, entry.Range = SourceKindVirtual, &rangeCopy
+	}
+	pm.playbackQueue[position] = entry
+	pm.queueModified()
+	return nil
+}
+
+func (pm *PlaybackManager) FindQueuePositionById(id int) (int, error) {
+	pm.playbackQueueLock.Lock()
+	defer pm.playbackQueueLock.Unlock()
+	return pm.findQueuePositionById(id)
+}
+
+func (pm *PlaybackManager) findQueuePositionById(id int) (int, error) {
+	for position, entry := range pm.playbackQueue {
+		if entry.ID == id {
+			return position, nil
+		}
+	}
+	return -1, fmt.Errorf("no such song id: %d", id)
+}
+
+// inserts entries which were already validated and returns their new IDs
+func (pm *PlaybackManager) insertEntriesToQueue(position int, entries []QueueEntry) []int {
+	ids := []int{}
+	for i := range entries {
+		pm.lastQueueEntryId++
+		entries[i].ID = pm.lastQueueEntryId
+		ids = append(ids, pm.lastQueueEntryId)
+	}
+	if len(entries) > 0 {
+		// the current entry shifts along with the insertion. When the queue has finished playing (QueuePosition is at the end),
+		// appended entries become the next ones to play while entries inserted before the end keep the queue finished
+		initialQueueLength := len(pm.playbackQueue)
+		if pm.QueuePosition > position || (pm.QueuePosition == position && pm.QueuePosition < initialQueueLength) {
+			pm.QueuePosition += len(entries)
+		}
+		pm.playbackQueue = append(pm.playbackQueue[:position], append(entries, pm.playbackQueue[position:]...)...)
+		pm.queueModified()
+		log.Printf("added %d entries to the queue", len(entries))
+	}
+	return ids
+}
+
+func (pm *PlaybackManager) deleteFromQueue(start, end int) error {
+	if start < 0 || end > len(pm.playbackQueue) || start >= end {
+		return fmt.Errorf("invalid queue range: %d:%d", start, end)
+	}
+	removingCurrentEntry := pm.QueuePosition >= start && pm.QueuePosition < end
+	wasPlaying := removingCurrentEntry && pm.audioPlayer != nil && !pm.audioPlayer.IsPaused()
+	if removingCurrentEntry && pm.audioPlayer != nil {
+		err := pm.stop()
+		if err != nil {
+			return err
+		}
+	}
+	pm.playbackQueue = append(pm.playbackQueue[:start], pm.playbackQueue[end:]...)
+	if removingCurrentEntry {
+		pm.QueuePosition = start
+	} else if pm.QueuePosition >= end {
+		pm.QueuePosition -= end - start
+	}
+	pm.queueModified()
+	if wasPlaying && pm.QueuePosition < len(pm.playbackQueue) {
+		return pm.play()
+	}
+	return nil
+}
+
+func (pm *PlaybackManager) moveInQueue(start, end, to int) error {
+	if start < 0 || end > len(pm.playbackQueue) || start >= end {
+		return fmt.Errorf("invalid queue range: %d:%d", start, end)
+	}
+	if to < 0 || to > len(pm.playbackQueue)-(end-start) {
+		return fmt.Errorf("invalid queue position: %d", to)
+	}
+	currentEntryId := pm.getCurrentEntryId()
+	moved := append([]QueueEntry{}, pm.playbackQueue[start:end]...)
+	remaining := append(pm.playbackQueue[:start:start], pm.playbackQueue[end:]...)
+	pm.playbackQueue = append(remaining[:to:to], append(moved, remaining[to:]...)...)
+	pm.followCurrentEntry(currentEntryId)
+	pm.queueModified()
+	return nil
+}
+
+func (pm *PlaybackManager) swapInQueue(position1, position2 int) error {
+	for _, position := range []int{position1, position2} {
+		if position < 0 || position >= len(pm.playbackQueue) {
+			return fmt.Errorf("invalid queue position: %d", position)
+		}
+	}
+	currentEntryId := pm.getCurrentEntryId()
+	pm.playbackQueue[position1], pm.playbackQueue[position2] = pm.playbackQueue[position2], pm.playbackQueue[position1]
+	pm.followCurrentEntry(currentEntryId)
+	pm.queueModified()
+	return nil
+}
+
+// returns the ID of the entry at QueuePosition, -1 when the queue has finished playing
+func (pm *PlaybackManager) getCurrentEntryId() int {
+	if pm.QueuePosition < len(pm.playbackQueue) {
+		return pm.playbackQueue[pm.QueuePosition].ID
+	}
+	return -1
+}
+
+// moves QueuePosition to wherever the current entry ended up after reordering the queue
+func (pm *PlaybackManager) followCurrentEntry(currentEntryId int) {
+	if currentEntryId == -1 {
+		return
+	}
+	position, err := pm.findQueuePositionById(currentEntryId)
+	if err == nil {
+		pm.QueuePosition = position
+	}
+}
+
+func newQueueEntries(uris []string) []QueueEntry {
+	entries := make([]QueueEntry, len(uris))
+	for i, uri := range uris {
+		entries[i] = NewQueueEntry(uri)
+	}
+	return entries
+}
+
+// validates the entries and looks up their metadata. It is called before locking the playback manager,
+// so that checking the file system for big additions does not hold up playback or other clients
+func (pm *PlaybackManager) prepareEntries(entries []QueueEntry) (valid []QueueEntry, rejected []string) {
+	valid = []QueueEntry{}
+	for _, entry := range entries {
+		if err := entry.validate(); err != nil {
+			log.Printf("could not add: %s, error: %v", entry.URI, err)
+			rejected = append(rejected, entry.URI)
+			continue
+		}
+		valid = append(valid, entry)
+	}
+	return pm.withMetadata(valid), rejected
+}
+
+// returns nil when nothing was rejected, so the result can be returned as an error directly
+func newAddError(rejected []string) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+	return &AddError{Rejected: rejected}
+}
+
+// fills in the metadata of local entries from the audio database. It is called before locking the playback manager,
+// so that slow lookups do not hold up playback
+func (pm *PlaybackManager) withMetadata(entries []QueueEntry) []QueueEntry {
+	pm.audioPlayerLock.Lock()
+	metadataResolver := pm.metadataResolver
+	pm.audioPlayerLock.Unlock()
+	if metadataResolver == nil {
+		return entries
+	}
+	for i := range entries {
+		if entries[i].Metadata == nil && !entries[i].metadataResolved && entries[i].IsLocal() {
+			entries[i].Metadata = metadataResolver(entries[i].URI)
+		}
+	}
+	return entries
+}
+
+func (pm *PlaybackManager) queueModified() {
+	pm.queueVersion++
+	pm.preloadNextTrack()
+	pm.publishQueueEvent(EventQueueChanged)
+	pm.notifier.Notify(idle.SubsystemPlaylist)
+}
@@ -0,0 +1,103 @@
+package playbackmanager
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/audioplayer"
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+// SourceKind tells where the audio of a queue entry comes from
+type SourceKind string
+
+const (
+	SourceKindFile    SourceKind = "file"    // a local audio file
+	SourceKindStream  SourceKind = "stream"  // audio streamed from an http(s) URL
+	SourceKindVirtual SourceKind = "virtual" // a part of a local audio file given by the range, like a track of a cue sheet
+)
+
+// TrackRange is the part of the source which is played, an End of 0 plays until the end of the source
+type TrackRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// QueueEntry is a single track in the playback queue.
+// The ID is assigned when the entry is added and never changes, so clients can address an entry even after the queue is reordered
+type QueueEntry struct {
+	ID       int
+	URI      string // path of local files, URL of streams
+	Kind     SourceKind
+	Range    *TrackRange                 // only set for virtual tracks
+	Metadata *database.AudioFileMetadata // cached when the entry is added, nil when the track is not in the audio database
+	Priority int                         // 0 to MaximumPriority, entries with a higher priority are played first
+
+	metadataResolved bool // the metadata was looked up before the entry was added, even if the track was not found
+}
+
+// NewQueueEntry creates an entry for a file path or a stream URL, the ID is assigned once it is added to the queue
+func NewQueueEntry(uri string) QueueEntry {
+	if strings.Contains(uri, "://") {
+		return QueueEntry{URI: uri, Kind: SourceKindStream}
+	}
+	return QueueEntry{URI: uri, Kind: SourceKindFile}
+}
+
+// NewResolvedQueueEntry creates an entry for a file whose metadata was already looked up, so that it is not looked up again.
+// The metadata may be nil for files which are not in the audio database
+func NewResolvedQueueEntry(uri string, metadata *database.AudioFileMetadata) QueueEntry {
+	entry := NewQueueEntry(uri)
+	entry.Metadata, entry.metadataResolved = metadata, true
+	return entry
+}
+
+// NewVirtualQueueEntry creates an entry which plays only the given range of a local file
+func NewVirtualQueueEntry(filePath string, trackRange TrackRange) QueueEntry {
+	return QueueEntry{URI: filePath, Kind: SourceKindVirtual, Range: &trackRange}
+}
+
+// IsLocal reports whether the entry is backed by a local file
+func (entry QueueEntry) IsLocal() bool {
+	return entry.Kind == SourceKindFile || entry.Kind == SourceKindVirtual
+}
+
+// checks that the entry can be played, before it is added to the queue
+func (entry QueueEntry) validate() error {
+	switch entry.Kind {
+	case SourceKindFile:
+		return audioplayer.IsFileSupported(entry.URI)
+	case SourceKindStream:
+		if !isStreamURI(entry.URI) {
+			return fmt.Errorf("only http(s) streams are supported: %s", entry.URI)
+		}
+		return nil
+	case SourceKindVirtual:
+		if entry.Range == nil {
+			return fmt.Errorf("virtual track without a range: %s", entry.URI)
+		}
+		if err := entry.Range.validate(); err != nil {
+			return err
+		}
+		return audioplayer.IsFileSupported(entry.URI)
+	}
+	return fmt.Errorf("unknown source kind: %s", entry.Kind)
+}
+
+func (trackRange TrackRange) validate() error {
+	if trackRange.Start < 0 || trackRange.End < 0 || (trackRange.End > 0 && trackRange.End <= trackRange.Start) {
+		return fmt.Errorf("invalid track range: %v-%v", trackRange.Start, trackRange.End)
+	}
+	return nil
+}
+
+func isStreamURI(uri string) bool {
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(parsedURL.Scheme)
+	return (scheme == "http" || scheme == "https") && parsedURL.Host != ""
+}
@@ -0,0 +1,234 @@
+package playbackmanager
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+const defaultQueueHistorySize = 50 // queue modifications which can be undone
+
+// queueState is the queue as it was before or after a modification
+type queueState struct {
+	queue          []QueueEntry
+	currentEntryId int // -1 when there was no current track
+	version        int
+}
+
+// queueChange is a single modification of the queue. Undoing it only reverts what the modification itself did,
+// entries added or removed automatically since, like by the auto DJ or by consume, are left as they are
+type queueChange struct {
+	before queueState
+	after  queueState
+}
+
+// queueStep is what a single undo reverts, one modification or several made as a unit, like the batches of an add job
+type queueStep struct {
+	id      int // the QueueStep the modifications were made in, 0 for a single modification
+	changes []queueChange
+}
+
+// QueueStep groups modifications, so that they are undone and redone together
+type QueueStep struct {
+	id int
+}
+
+// queueHistory keeps the steps to undo, and the undone ones to apply again with redo
+type queueHistory struct {
+	undo       []queueStep
+	redo       []queueStep
+	size       int
+	lastStepId int
+}
+
+func newQueueHistory(size int) *queueHistory {
+	return &queueHistory{undo: []queueStep{}, redo: []queueStep{}, size: size}
+}
+
+// remembers a new modification, which makes the undone ones unreachable. A modification made in the same step as the
+// last one is added to it, as long as no other modification was remembered in between
+func (history *queueHistory) push(stepId int, change queueChange) {
+	history.redo = []queueStep{}
+	if last := len(history.undo) - 1; stepId != 0 && last >= 0 && history.undo[last].id == stepId {
+		history.undo[last].changes = append(history.undo[last].changes, change)
+		return
+	}
+	history.undo = appendBounded(history.undo, queueStep{id: stepId, changes: []queueChange{change}}, history.size)
+}
+
+func appendBounded(steps []queueStep, step queueStep, size int) []queueStep {
+	steps = append(steps, step)
+	if len(steps) > size {
+		steps = steps[len(steps)-size:]
+	}
+	return steps
+}
+
+func popStep(steps []queueStep) ([]queueStep, queueStep, bool) {
+	if len(steps) == 0 {
+		return steps, queueStep{}, false
+	}
+	return steps[:len(steps)-1], steps[len(steps)-1], true
+}
+
+// NewQueueStep starts a step which the modifications made with it are undone in, see AddEntriesToQueueInStep
+func (pm *PlaybackManager) NewQueueStep() QueueStep {
+	pm.playbackQueueLock.Lock()
+	defer pm.playbackQueueLock.Unlock()
+	pm.queueHistory.lastStepId++
+	return QueueStep{id: pm.queueHistory.lastStepId}
+}
+
+// Undo reverts the last modification of the queue. Playback goes on undisturbed if the current track is still in the
+// queue afterwards, and when the queue was cleared the entry which was current back then is loaded again
+func (pm *PlaybackManager) Undo() error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	undo, step, found := popStep(pm.queueHistory.undo)
+	if !found {
+		return fmt.Errorf("nothing to undo")
+	}
+	pm.queueHistory.undo = undo
+	pm.queueHistory.redo = appendBounded(pm.queueHistory.redo, step, pm.queueHistory.size)
+	log.Printf("undoing the last queue modification")
+	queue := pm.playbackQueue
+	for i := len(step.changes) - 1; i >= 0; i-- {
+		queue = applyQueueChange(queue, step.changes[i].after.queue, step.changes[i].before.queue)
+	}
+	return pm.replaceQueue(queue, step.changes[0].before.currentEntryId)
+}
+
+// Redo applies the last undone modification again
+func (pm *PlaybackManager) Redo() error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	redo, step, found := popStep(pm.queueHistory.redo)
+	if !found {
+		return fmt.Errorf("nothing to redo")
+	}
+	pm.queueHistory.redo = redo
+	pm.queueHistory.undo = appendBounded(pm.queueHistory.undo, step, pm.queueHistory.size)
+	log.Printf("redoing the last undone queue modification")
+	queue := pm.playbackQueue
+	for _, change := range step.changes {
+		queue = applyQueueChange(queue, change.before.queue, change.after.queue)
+	}
+	return pm.replaceQueue(queue, step.changes[len(step.changes)-1].after.currentEntryId)
+}
+
+// remembers the queue from before a modification, so that the modification can be undone. It is deferred with the
+// state taken before modifying the queue, and does nothing when the queue was not modified after all
+func (pm *PlaybackManager) rememberQueueState(state queueState) {
+	pm.rememberQueueStep(state, QueueStep{})
+}
+
+// same as rememberQueueState for a modification made as part of a step
+func (pm *PlaybackManager) rememberQueueStep(state queueState, step QueueStep) {
+	if pm.queueVersion != state.version {
+		pm.queueHistory.push(step.id, queueChange{before: state, after: pm.getQueueState()})
+	}
+}
+
+func (pm *PlaybackManager) getQueueState() queueState {
+	return queueState{
+		queue:          append([]QueueEntry{}, pm.playbackQueue...),
+		currentEntryId: pm.getCurrentEntryId(),
+		version:        pm.queueVersion,
+	}
+}
+
+// turns the queue from the state of one side of a change into the other one. Only the differences between the two sides
+// are applied: entries which are only in from are removed, the ones which are only in to are inserted after the entry
+// preceding them in to, and the entries of to are put back in its order. Every other entry stays where it is
+func applyQueueChange(queue []QueueEntry, from []QueueEntry, to []QueueEntry) []QueueEntry {
+	inFrom, order := map[int]bool{}, map[int]int{}
+	for _, entry := range from {
+		inFrom[entry.ID] = true
+	}
+	for position, entry := range to {
+		order[entry.ID] = position
+	}
+
+	changed := []QueueEntry{}
+	for _, entry := range queue {
+		if _, inTo := order[entry.ID]; !inFrom[entry.ID] || inTo {
+			changed = append(changed, entry)
+		}
+	}
+	for i, entry := range to {
+		if inFrom[entry.ID] || findEntryPosition(changed, entry.ID) != -1 {
+			continue
+		}
+		position := 0
+		for j := i - 1; j >= 0; j-- {
+			if preceding := findEntryPosition(changed, to[j].ID); preceding != -1 {
+				position = preceding + 1
+				break
+			}
+		}
+		changed = append(changed[:position], append([]QueueEntry{entry}, changed[position:]...)...)
+	}
+
+	slots, entries := []int{}, []QueueEntry{}
+	for position, entry := range changed {
+		if _, inTo := order[entry.ID]; inTo {
+			slots, entries = append(slots, position), append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return order[entries[i].ID] < order[entries[j].ID] })
+	for i, slot := range slots {
+		changed[slot] = entries[i]
+	}
+	return changed
+}
+
+func findEntryPosition(entries []QueueEntry, id int) int {
+	for position, entry := range entries {
+		if entry.ID == id {
+			return position
+		}
+	}
+	return -1
+}
+
+// replaces the queue after an undo or redo. If the current entry was removed, playback continues with the entry taking
+// its place like after deleting it, and when the queue was empty, the entry which was current in the restored state is loaded
+func (pm *PlaybackManager) replaceQueue(queue []QueueEntry, restoredCurrentEntryId int) error {
+	currentEntryId := pm.getCurrentEntryId()
+	currentPosition := findEntryPosition(queue, currentEntryId)
+	wasPlaying := pm.audioPlayer != nil && !pm.audioPlayer.IsPaused()
+	if currentEntryId != -1 && currentPosition == -1 && pm.audioPlayer != nil {
+		err := pm.stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case currentPosition != -1:
+		pm.QueuePosition = currentPosition
+	case currentEntryId != -1:
+		// the entries before the current one which are still there
+		remaining := 0
+		for _, entry := range pm.playbackQueue[:pm.QueuePosition] {
+			if findEntryPosition(queue, entry.ID) != -1 {
+				remaining++
+			}
+		}
+		pm.QueuePosition = remaining
+	case len(pm.playbackQueue) == 0 && findEntryPosition(queue, restoredCurrentEntryId) != -1:
+		pm.QueuePosition = findEntryPosition(queue, restoredCurrentEntryId)
+	default:
+		pm.QueuePosition = len(queue)
+	}
+	pm.playbackQueue = queue
+	pm.queueModified()
+	if pm.audioPlayer == nil && wasPlaying && pm.QueuePosition < len(pm.playbackQueue) {
+		return pm.play()
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package playbackmanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUndoRedo(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3", "../../music/sample-12s.mp3"), t)
+	if err := playbackManager.Redo(); err == nil {
+		t.Errorf("expected nothing to redo")
+	}
+
+	// the current track keeps playing through modifications which leave it in the queue
+	checkError(playbackManager.PlayQueuePosition(1), t)
+	defer playbackManager.Stop()
+	player := playbackManager.audioPlayer
+	checkError(playbackManager.MoveInQueue(0, 1, 2), t)
+	checkError(playbackManager.DeleteFromQueue(2, 3), t)
+	checkError(playbackManager.Undo(), t)
+	checkError(playbackManager.Undo(), t)
+	expected := []string{"sample-3s.mp3", "sample-9s.mp3", "sample-12s.mp3"}
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected: %v, got: %v", expected, names)
+	}
+	if playbackManager.audioPlayer != player || playbackManager.QueuePosition != 1 || playbackManager.GetStatus().State != PlaybackStatePlaying {
+		t.Errorf("expected playback to go on undisturbed: %+v", playbackManager.GetStatus())
+	}
+	checkError(playbackManager.Redo(), t)
+	expected = []string{"sample-9s.mp3", "sample-12s.mp3", "sample-3s.mp3"}
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) || playbackManager.QueuePosition != 0 {
+		t.Errorf("expected: %v at position 0, got: %v at %d", expected, names, playbackManager.QueuePosition)
+	}
+
+	// a clear is undone with the entry which was current back then
+	checkError(playbackManager.ClearQueue(), t)
+	checkError(playbackManager.Undo(), t)
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) || playbackManager.QueuePosition != 0 {
+		t.Errorf("expected: %v at position 0, got: %v at %d", expected, names, playbackManager.QueuePosition)
+	}
+	checkError(playbackManager.ShuffleQueue(0, 3), t)
+	if err := playbackManager.Redo(); err == nil {
+		t.Errorf("expected a new modification to drop the undone ones")
+	}
+
+	if err := playbackManager.DeleteFromQueue(5, 6); err == nil {
+		t.Fatalf("expected an error for an invalid range")
+	}
+	checkError(playbackManager.Undo(), t)
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected a failed modification not to be remembered, got: %v", names)
+	}
+}
+
+func TestUndoLeavesAutomaticChanges(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3"), t)
+	checkError(playbackManager.AddAutomaticAudioFilesToQueue("../../music/sample-12s.mp3"), t)
+	checkError(playbackManager.DeleteFromQueue(0, 1), t)
+	checkError(playbackManager.Undo(), t)
+	expected := []string{"sample-3s.mp3", "sample-9s.mp3", "sample-12s.mp3"}
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the automatic addition to stay, expected: %v, got: %v", expected, names)
+	}
+
+	// an entry consumed after a modification is not brought back by undoing it
+	checkError(playbackManager.MoveInQueue(2, 3, 0), t)
+	playbackManager.QueuePosition = 1
+	playbackManager.consumeCurrentEntry()
+	checkError(playbackManager.Undo(), t)
+	expected = []string{"sample-9s.mp3", "sample-12s.mp3"}
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the consumed entry to stay removed, expected: %v, got: %v", expected, names)
+	}
+
+	// the additions of a step are undone together
+	step := playbackManager.NewQueueStep()
+	checkError(playbackManager.AddEntriesToQueueInStep(step, NewQueueEntry("../../music/sample-15s.mp3")), t)
+	checkError(playbackManager.AddEntriesToQueueInStep(step, NewQueueEntry("../../music/sample-3s.mp3")), t)
+	checkError(playbackManager.Undo(), t)
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected both additions to be undone, expected: %v, got: %v", expected, names)
+	}
+	checkError(playbackManager.Redo(), t)
+	expected = append(expected, "sample-15s.mp3", "sample-3s.mp3")
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected both additions to be redone, expected: %v, got: %v", expected, names)
+	}
+}
+
+func TestQueueHistoryIsBounded(t *testing.T) {
+	history := newQueueHistory(2)
+	for version := 0; version < 3; version++ {
+		history.push(0, queueChange{before: queueState{version: version}})
+	}
+	if len(history.undo) != 2 || history.undo[0].changes[0].before.version != 1 {
+		t.Errorf("expected only the 2 most recent steps, got: %+v", history.undo)
+	}
+	history.push(1, queueChange{})
+	history.push(1, queueChange{})
+	if len(history.undo) != 2 || len(history.undo[1].changes) != 2 {
+		t.Errorf("expected the changes of the same step to be kept together, got: %+v", history.undo)
+	}
+}
@@ -0,0 +1,139 @@
+package playbackmanager
+
+import (
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+func getQueueFileNames(playbackManager *PlaybackManager) []string {
+	names := []string{}
+	for _, entry := range playbackManager.GetQueue() {
+		names = append(names, path.Base(entry.URI))
+	}
+	return names
+}
+
+func TestQueueEditing(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	err := playbackManager.AddAudioFilesToQueue(
+		"../../music/sample-3s.mp3",
+		"../../music/sample-9s.mp3",
+		"../../music/sample-12s.mp3",
+		"../../music/sample-15s.mp3",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, entry := range playbackManager.GetQueue() {
+		ids = append(ids, entry.ID)
+	}
+
+	// make sample-12s.mp3 the current track and check that QueuePosition follows it around
+	_ = playbackManager.PlayQueuePosition(2)
+	_ = playbackManager.Pause()
+
+	checkError(playbackManager.MoveInQueue(2, 4, 0), t)
+	expected := []string{"sample-12s.mp3", "sample-15s.mp3", "sample-3s.mp3", "sample-9s.mp3"}
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected: %v, got: %v", expected, names)
+	}
+	if status := playbackManager.GetStatus(); status.QueuePosition != 0 || status.CurrentId != ids[2] {
+		t.Errorf("current entry was not followed after move: %+v", status)
+	}
+
+	checkError(playbackManager.SwapIdsInQueue(ids[2], ids[1]), t)
+	if status := playbackManager.GetStatus(); status.QueuePosition != 3 || status.CurrentId != ids[2] {
+		t.Errorf("current entry was not followed after swap: %+v", status)
+	}
+
+	newIds, err := playbackManager.InsertAudioFilesToQueue(0, "../../music/sample-3s.mp3")
+	checkError(err, t)
+	if len(newIds) != 1 || newIds[0] <= ids[3] {
+		t.Errorf("expected a new unique id, got: %v", newIds)
+	}
+	if status := playbackManager.GetStatus(); status.QueuePosition != 4 {
+		t.Errorf("current entry was not followed after insert: %+v", status)
+	}
+
+	// deleting the current entry makes the following entry current, here the queue is finished instead
+	checkError(playbackManager.DeleteIdFromQueue(ids[2]), t)
+	status := playbackManager.GetStatus()
+	if status.QueueLength != 4 || status.QueuePosition != 4 || status.State != PlaybackStateStopped {
+		t.Errorf("unexpected status after deleting the current entry: %+v", status)
+	}
+
+	checkError(playbackManager.DeleteFromQueue(0, 2), t)
+	expected = []string{"sample-15s.mp3", "sample-3s.mp3"}
+	if names := getQueueFileNames(playbackManager); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected: %v, got: %v", expected, names)
+	}
+
+	if err := playbackManager.MoveInQueue(0, 1, 5); err == nil {
+		t.Error("expected an error when moving past the end of the queue")
+	}
+
+	checkError(playbackManager.ClearQueue(), t)
+	if status := playbackManager.GetStatus(); status.QueueLength != 0 || status.QueuePosition != 0 {
+		t.Errorf("unexpected status after clearing the queue: %+v", status)
+	}
+}
+
+func TestQueueEntryKinds(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	resolved := []string{}
+	playbackManager.SetMetadataResolver(func(filePath string) *database.AudioFileMetadata {
+		resolved = append(resolved, filePath)
+		return &database.AudioFileMetadata{Title: []string{path.Base(filePath)}}
+	})
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-12s.mp3", "https://radio.example.com/live.mp3"), t)
+	_, err := playbackManager.InsertEntriesToQueue(0, NewVirtualQueueEntry("../../music/sample-12s.mp3", TrackRange{Start: time.Second * 2, End: time.Second * 5}))
+	checkError(err, t)
+	if err := playbackManager.AddAudioFilesToQueue("ftp://example.com/track.mp3"); err == nil {
+		t.Error("expected an error for an unsupported URL")
+	}
+
+	queue := playbackManager.GetQueue()
+	kinds := []SourceKind{}
+	for _, entry := range queue {
+		kinds = append(kinds, entry.Kind)
+	}
+	if expected := []SourceKind{SourceKindVirtual, SourceKindFile, SourceKindStream}; !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected kinds: %v, got: %v", expected, kinds)
+	}
+	if len(resolved) != 2 || queue[1].Metadata == nil || queue[2].Metadata != nil {
+		t.Errorf("expected the metadata of local entries to be cached once, resolved: %v", resolved)
+	}
+	// entries whose metadata was looked up already are not looked up again, even when the file is not in the audio database
+	checkError(playbackManager.AddEntriesToQueue(NewResolvedQueueEntry("../../music/sample-9s.mp3", nil)), t)
+	if len(resolved) != 2 || playbackManager.GetQueue()[3].Metadata != nil {
+		t.Errorf("expected resolved entries to be added as they are, resolved: %v", resolved)
+	}
+
+	checkError(playbackManager.PlayQueuePosition(0), t)
+	checkError(playbackManager.Pause(), t)
+	if duration := playbackManager.GetStatus().Duration; duration != time.Second*3 {
+		t.Errorf("expected the virtual track to last as long as its range, got: %v", duration)
+	}
+	if err := playbackManager.SetEntryRange(queue[0].ID, nil); err == nil {
+		t.Error("expected an error for changing the range of the current track")
+	}
+	checkError(playbackManager.SetEntryRange(queue[1].ID, &TrackRange{Start: time.Second}), t)
+	if entry := playbackManager.GetQueue()[1]; entry.Kind != SourceKindVirtual || entry.Range.Start != time.Second {
+		t.Errorf("expected the entry to become a virtual track: %+v", entry)
+	}
+	if err := playbackManager.PlayQueuePosition(2); err == nil {
+		t.Error("expected an error for playing a stream")
+	}
+}
+
+func checkError(err error, t *testing.T) {
+	t.Helper()
+	if err != nil {
+		t.Error(err)
+	}
+}
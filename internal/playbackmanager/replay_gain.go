@@ -0,0 +1,52 @@
+package playbackmanager
+
+import (
+	"log"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/replaygain"
+)
+
+func (pm *PlaybackManager) GetReplayGainSettings() replaygain.Settings {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	return pm.replayGainSettings
+}
+
+// SetReplayGainSettings applies the settings to the current track right away
+func (pm *PlaybackManager) SetReplayGainSettings(settings replaygain.Settings) {
+	pm.updateReplayGainSettings(func(currentSettings *replaygain.Settings) { *currentSettings = settings })
+}
+
+func (pm *PlaybackManager) SetReplayGainMode(mode replaygain.Mode) {
+	pm.updateReplayGainSettings(func(settings *replaygain.Settings) { settings.Mode = mode })
+}
+
+func (pm *PlaybackManager) updateReplayGainSettings(update func(settings *replaygain.Settings)) {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	update(&pm.replayGainSettings)
+	if pm.audioPlayer != nil {
+		pm.audioPlayer.Volume.SetGain(pm.getReplayGainScale(pm.playbackQueue[pm.QueuePosition]))
+	}
+	pm.preloadNextTrack()
+	pm.notifier.Notify(idle.SubsystemOptions)
+}
+
+// returns the factor by which the samples of a track are scaled. ReplayGain values are read from the tags of
+// the file, falling back to the custom tags in the audio database for files whose tags can not be read
+func (pm *PlaybackManager) getReplayGainScale(entry QueueEntry) float64 {
+	if pm.replayGainSettings.Mode == replaygain.ModeOff {
+		return 1
+	}
+	info, err := replaygain.ReadFile(entry.URI)
+	if err != nil {
+		log.Printf("could not read replay gain of: %s, error: %v", entry.URI, err)
+	}
+	if !info.HasTrackGain && !info.HasAlbumGain && entry.Metadata != nil {
+		info = replaygain.ParseTags(entry.Metadata.CustomTags)
+	}
+	return pm.replayGainSettings.GetScale(info, pm.options.Random)
+}
@@ -0,0 +1,130 @@
+package playbackmanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+type SeekMode int
+
+const (
+	SeekAbsolute   SeekMode = iota // Time from the start of the track
+	SeekRelative                   // Time from the current position, the result is clamped to the track bounds
+	SeekPercentage                 // Percentage of the track duration
+)
+
+// SeekTarget describes where to seek to within a track
+type SeekTarget struct {
+	Mode       SeekMode
+	Time       time.Duration
+	Percentage float64
+}
+
+// resolves the target to a time from the start of the track, elapsed is the current position in the track
+func (target SeekTarget) resolve(elapsed, duration time.Duration) (time.Duration, error) {
+	switch target.Mode {
+	case SeekAbsolute:
+		if target.Time < 0 {
+			return 0, fmt.Errorf("cannot seek to a negative time: %v", target.Time)
+		}
+		if target.Time > duration {
+			return 0, fmt.Errorf("cannot seek to %v, the track is only %v long", target.Time, duration.Truncate(time.Millisecond))
+		}
+		return target.Time, nil
+	case SeekRelative:
+		return min(max(elapsed+target.Time, 0), duration), nil
+	case SeekPercentage:
+		if target.Percentage < 0 || target.Percentage > 100 {
+			return 0, fmt.Errorf("seek percentage must be between 0 and 100, got: %v", target.Percentage)
+		}
+		return time.Duration(float64(duration) * target.Percentage / 100), nil
+	}
+	return 0, fmt.Errorf("unknown seek mode: %d", target.Mode)
+}
+
+// Seek moves to the given time from the start of the current track
+func (pm *PlaybackManager) Seek(seekTime time.Duration) error {
+	return pm.SeekCurrent(SeekTarget{Mode: SeekAbsolute, Time: seekTime})
+}
+
+// SeekCurrent seeks within the current track, without changing whether it is playing or paused
+func (pm *PlaybackManager) SeekCurrent(target SeekTarget) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if pm.QueuePosition == len(pm.playbackQueue) || pm.audioPlayer == nil {
+		return fmt.Errorf("no active audio file in queue")
+	}
+	return pm.seekCurrent(target)
+}
+
+// SeekQueuePosition seeks within the entry at position, which starts playing if it is not the current track.
+// Relative targets are taken from the start of the track in that case
+func (pm *PlaybackManager) SeekQueuePosition(position int, target SeekTarget) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	return pm.seekQueuePosition(position, target)
+}
+
+func (pm *PlaybackManager) SeekId(id int, target SeekTarget) error {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	position, err := pm.findQueuePositionById(id)
+	if err != nil {
+		return err
+	}
+	return pm.seekQueuePosition(position, target)
+}
+
+func (pm *PlaybackManager) seekQueuePosition(position int, target SeekTarget) error {
+	if position < 0 || position >= len(pm.playbackQueue) {
+		return fmt.Errorf("invalid queue position: %d", position)
+	}
+	if position == pm.QueuePosition && pm.audioPlayer != nil {
+		return pm.seekCurrent(target)
+	}
+	if pm.audioPlayer != nil {
+		err := pm.stop()
+		if err != nil {
+			return err
+		}
+	}
+	pm.QueuePosition = position
+	err := pm.createAudioPlayerForCurrentTrack()
+	if err != nil {
+		return err
+	}
+	// seeking before starting playback keeps the start of the track from being audible
+	err = pm.seekCurrent(target)
+	if err != nil {
+		_ = pm.stop()
+		return err
+	}
+	return pm.play()
+}
+
+func (pm *PlaybackManager) seekCurrent(target SeekTarget) error {
+	from := pm.audioPlayer.GetCurrentPosition()
+	seekTime, err := target.resolve(from, pm.audioPlayer.GetDuration())
+	if err != nil {
+		return err
+	}
+	err = pm.sequencer.SeekCurrent(seekTime)
+	if err != nil {
+		return err
+	}
+	if pm.options.Crossfade > 0 {
+		// the preloaded track may already have started fading in
+		pm.reloadNextTrack()
+	}
+	pm.publishSeeked(from)
+	pm.notifier.Notify(idle.SubsystemPlayer)
+	return nil
+}
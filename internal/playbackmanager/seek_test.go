@@ -0,0 +1,56 @@
+package playbackmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveSeekTarget(t *testing.T) {
+	elapsed, duration := time.Second*10, time.Second*30
+	testCases := []struct {
+		target   SeekTarget
+		expected time.Duration
+		isError  bool
+	}{
+		{SeekTarget{Mode: SeekAbsolute, Time: time.Millisecond * 1500}, time.Millisecond * 1500, false},
+		{SeekTarget{Mode: SeekAbsolute, Time: time.Second * 31}, 0, true},
+		{SeekTarget{Mode: SeekAbsolute, Time: -time.Second}, 0, true},
+		{SeekTarget{Mode: SeekRelative, Time: time.Second * 5}, time.Second * 15, false},
+		{SeekTarget{Mode: SeekRelative, Time: -time.Second * 15}, 0, false},
+		{SeekTarget{Mode: SeekRelative, Time: time.Minute}, duration, false},
+		{SeekTarget{Mode: SeekPercentage, Percentage: 50}, time.Second * 15, false},
+		{SeekTarget{Mode: SeekPercentage, Percentage: 101}, 0, true},
+	}
+	for _, testCase := range testCases {
+		seekTime, err := testCase.target.resolve(elapsed, duration)
+		if (err != nil) != testCase.isError || seekTime != testCase.expected {
+			t.Errorf("%+v: expected (%v, error: %v), got (%v, %v)", testCase.target, testCase.expected, testCase.isError, seekTime, err)
+		}
+	}
+}
+
+func TestSeek(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-12s.mp3"), t)
+	checkError(playbackManager.Play(), t)
+	checkError(playbackManager.Pause(), t)
+	defer playbackManager.Stop()
+
+	checkError(playbackManager.Seek(time.Millisecond*1250), t)
+	if elapsed := playbackManager.GetStatus().Elapsed; elapsed != time.Millisecond*1250 {
+		t.Errorf("expected a sub-second accurate seek, got: %v", elapsed)
+	}
+	checkError(playbackManager.SeekCurrent(SeekTarget{Mode: SeekRelative, Time: -time.Millisecond * 250}), t)
+	if elapsed := playbackManager.GetStatus().Elapsed; elapsed != time.Second {
+		t.Errorf("expected a relative seek back to 1s, got: %v", elapsed)
+	}
+	if err := playbackManager.Seek(time.Minute); err == nil {
+		t.Error("expected an error for seeking beyond the end of the track")
+	}
+
+	checkError(playbackManager.SeekQueuePosition(1, SeekTarget{Mode: SeekAbsolute, Time: time.Second * 2}), t)
+	status := playbackManager.GetStatus()
+	if status.QueuePosition != 1 || status.State != PlaybackStatePlaying || status.Elapsed < time.Second*2 {
+		t.Errorf("expected the second entry to play from 2s: %+v", status)
+	}
+}
@@ -0,0 +1,167 @@
+package playbackmanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+const sleepFadeStep = time.Millisecond * 100 // how often the volume is lowered while fading out
+
+// SleepTimer stops playback at night, either at a given time or once the current track or album is over
+type SleepTimer struct {
+	Deadline         time.Time     // when playback is paused, zero when no time is set
+	FadeOut          time.Duration // the volume is lowered gradually over this long before the deadline
+	StopAfterCurrent bool          // stop once the current track played until its end
+	StopAfterAlbum   bool          // stop once the last consecutive track of the current album played until its end
+}
+
+// IsSet reports whether the timer will stop playback at some point
+func (sleepTimer SleepTimer) IsSet() bool {
+	return !sleepTimer.Deadline.IsZero() || sleepTimer.StopAfterCurrent || sleepTimer.StopAfterAlbum
+}
+
+func (pm *PlaybackManager) GetSleepTimer() SleepTimer {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	return pm.sleepTimer
+}
+
+// SetSleepTimer pauses playback after the given duration, fading out over the last fadeOut of it.
+// It replaces a previously set time but keeps the stop after current and stop after album flags
+func (pm *PlaybackManager) SetSleepTimer(duration, fadeOut time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("invalid sleep timer duration: %v", duration)
+	}
+	if fadeOut < 0 {
+		return fmt.Errorf("invalid fade out duration: %v", fadeOut)
+	}
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	pm.stopSleepCountdown()
+	pm.sleepTimer.Deadline = time.Now().Add(duration)
+	pm.sleepTimer.FadeOut = min(fadeOut, duration)
+	pm.sleepTimerCancel = make(chan struct{})
+	go pm.runSleepCountdown(pm.sleepTimer.Deadline, pm.sleepTimer.FadeOut, pm.sleepTimerCancel)
+	pm.notifier.Notify(idle.SubsystemOptions)
+	return nil
+}
+
+// SetStopAfterCurrent stops playback once the current track played until its end, the queue then points at the next track.
+// A loop of the current track is cleared, otherwise the track would never end
+func (pm *PlaybackManager) SetStopAfterCurrent(stopAfterCurrent bool) {
+	pm.setSleepFlags(func(sleepTimer *SleepTimer) { sleepTimer.StopAfterCurrent = stopAfterCurrent })
+}
+
+// SetStopAfterAlbum stops playback once the next track belongs to another album than the current one, clearing a loop like SetStopAfterCurrent
+func (pm *PlaybackManager) SetStopAfterAlbum(stopAfterAlbum bool) {
+	pm.setSleepFlags(func(sleepTimer *SleepTimer) { sleepTimer.StopAfterAlbum = stopAfterAlbum })
+}
+
+// CancelSleepTimer cancels the time and the stop after flags, restoring the volume if it was fading out
+func (pm *PlaybackManager) CancelSleepTimer() {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	pm.stopSleepCountdown()
+	pm.sleepTimer = SleepTimer{}
+	pm.preloadNextTrack()
+	pm.notifier.Notify(idle.SubsystemOptions)
+}
+
+func (pm *PlaybackManager) setSleepFlags(update func(sleepTimer *SleepTimer)) {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	update(&pm.sleepTimer)
+	if (pm.sleepTimer.StopAfterCurrent || pm.sleepTimer.StopAfterAlbum) && pm.audioPlayer != nil {
+		pm.clearLoop()
+	}
+	// the track after the current one must not start playing in the sequencer when playback stops after it
+	pm.preloadNextTrack()
+	pm.notifier.Notify(idle.SubsystemOptions)
+}
+
+// reports whether playback stops once the current track is over instead of continuing with the entry at nextPosition
+func (pm *PlaybackManager) shouldSleepAfterCurrent(nextPosition int) bool {
+	if pm.sleepTimer.StopAfterCurrent {
+		return true
+	}
+	if !pm.sleepTimer.StopAfterAlbum || nextPosition >= len(pm.playbackQueue) || pm.QueuePosition >= len(pm.playbackQueue) {
+		return false
+	}
+	currentAlbum := getAlbum(pm.playbackQueue[pm.QueuePosition])
+	return currentAlbum == "" || currentAlbum != getAlbum(pm.playbackQueue[nextPosition])
+}
+
+// clears the stop after flags once playback stopped because of them
+func (pm *PlaybackManager) sleepAfterCurrentDone() {
+	if pm.sleepTimer.StopAfterCurrent || pm.sleepTimer.StopAfterAlbum {
+		pm.sleepTimer.StopAfterCurrent, pm.sleepTimer.StopAfterAlbum = false, false
+		pm.notifier.Notify(idle.SubsystemOptions)
+	}
+}
+
+// waits for the fade out to begin, lowers the volume step by step and pauses playback at the deadline
+func (pm *PlaybackManager) runSleepCountdown(deadline time.Time, fadeOut time.Duration, cancel chan struct{}) {
+	select {
+	case <-time.After(time.Until(deadline.Add(-fadeOut))):
+	case <-cancel:
+		return
+	}
+	ticker := time.NewTicker(sleepFadeStep)
+	defer ticker.Stop()
+	for remaining := time.Until(deadline); fadeOut > 0 && remaining > 0; remaining = time.Until(deadline) {
+		pm.setSleepFade(float64(remaining)/float64(fadeOut), cancel)
+		select {
+		case <-ticker.C:
+		case <-cancel:
+			return
+		}
+	}
+	pm.sleepCountdownExpired(cancel)
+}
+
+func (pm *PlaybackManager) setSleepFade(fadeGain float64, cancel chan struct{}) {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	if pm.sleepTimerCancel != cancel {
+		return
+	}
+	pm.sleepFadeGain = fadeGain
+	pm.applyGain()
+}
+
+func (pm *PlaybackManager) sleepCountdownExpired(cancel chan struct{}) {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if pm.sleepTimerCancel != cancel {
+		return
+	}
+	if pm.audioPlayer != nil && !pm.audioPlayer.IsPaused() {
+		_ = pm.pause()
+	}
+	pm.sleepTimerCancel = nil
+	pm.sleepTimer.Deadline, pm.sleepTimer.FadeOut = time.Time{}, 0
+	pm.sleepFadeGain = 1
+	pm.applyGain()
+	pm.notifier.Notify(idle.SubsystemOptions)
+}
+
+// stops a running countdown and restores the volume, must be called with audioPlayerLock held
+func (pm *PlaybackManager) stopSleepCountdown() {
+	if pm.sleepTimerCancel != nil {
+		close(pm.sleepTimerCancel)
+		pm.sleepTimerCancel = nil
+	}
+	pm.sleepTimer.Deadline, pm.sleepTimer.FadeOut = time.Time{}, 0
+	if pm.sleepFadeGain != 1 {
+		pm.sleepFadeGain = 1
+		pm.applyGain()
+	}
+}
@@ -0,0 +1,91 @@
+package playbackmanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+)
+
+func TestSleepTimerPausesPlayback(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	subscription := playbackManager.Subscribe(0)
+	defer subscription.Unsubscribe()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-9s.mp3"), t)
+	checkError(playbackManager.Play(), t)
+	defer playbackManager.Stop()
+
+	checkError(playbackManager.SetSleepTimer(time.Millisecond*500, time.Millisecond*300), t)
+	if !playbackManager.GetSleepTimer().IsSet() {
+		t.Fatal("expected the sleep timer to be set")
+	}
+	time.Sleep(time.Millisecond * 350)
+	playbackManager.audioPlayerLock.Lock()
+	fadeGain := playbackManager.sleepFadeGain
+	playbackManager.audioPlayerLock.Unlock()
+	if fadeGain <= 0 || fadeGain >= 1 {
+		t.Errorf("expected the volume to be fading out, got a fade gain of: %v", fadeGain)
+	}
+
+	waitForEvent(t, subscription, EventPaused, 2*time.Second)
+	if playbackManager.GetSleepTimer().IsSet() || playbackManager.sleepFadeGain != 1 {
+		t.Errorf("expected the sleep timer to be reset, got: %+v with a fade gain of %v", playbackManager.GetSleepTimer(), playbackManager.sleepFadeGain)
+	}
+
+	checkError(playbackManager.SetSleepTimer(time.Millisecond*100, 0), t)
+	playbackManager.CancelSleepTimer()
+	time.Sleep(time.Millisecond * 200)
+	if playbackManager.GetSleepTimer().IsSet() {
+		t.Error("expected the sleep timer to be cancelled")
+	}
+}
+
+func TestStopAfterCurrent(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3", "../../music/sample-12s.mp3"), t)
+	for i, album := range []string{"first", "first", "second"} {
+		playbackManager.playbackQueue[i].Metadata = &database.AudioFileMetadata{Album: []string{album}}
+	}
+
+	testCases := []struct {
+		position         int
+		stopAfterCurrent bool
+		stopAfterAlbum   bool
+		shouldStop       bool
+	}{
+		{0, false, false, false},
+		{0, true, false, true},
+		{0, false, true, false},
+		{1, false, true, true},
+	}
+	for _, testCase := range testCases {
+		playbackManager.QueuePosition = testCase.position
+		playbackManager.SetStopAfterCurrent(testCase.stopAfterCurrent)
+		playbackManager.SetStopAfterAlbum(testCase.stopAfterAlbum)
+		position, shouldStop := playbackManager.getNextQueuePosition(true)
+		if position != testCase.position+1 || shouldStop != testCase.shouldStop {
+			t.Errorf("%+v: got position %d, stop %v", testCase, position, shouldStop)
+		}
+		if _, shouldStop := playbackManager.getNextQueuePosition(false); shouldStop {
+			t.Errorf("%+v: skipping a track should not stop playback", testCase)
+		}
+	}
+
+	playbackManager.QueuePosition = 0
+	playbackManager.SetStopAfterCurrent(true)
+	checkError(playbackManager.Play(), t)
+	defer playbackManager.Stop()
+	if playbackManager.preloadedPlayer != nil {
+		t.Error("nothing should be preloaded when stopping after the current track")
+	}
+	playbackManager.audioPlayerLock.Lock()
+	playbackManager.playbackQueueLock.Lock()
+	err := playbackManager.advance(true)
+	playbackManager.audioPlayerLock.Unlock()
+	playbackManager.playbackQueueLock.Unlock()
+	checkError(err, t)
+	status := playbackManager.GetStatus()
+	if status.State != PlaybackStateStopped || status.QueuePosition != 1 || playbackManager.GetSleepTimer().IsSet() {
+		t.Errorf("expected playback to stop at the next track and the flag to be cleared, got: %+v", status)
+	}
+}
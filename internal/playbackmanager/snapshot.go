@@ -0,0 +1,102 @@
+package playbackmanager
+
+import (
+	"log"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+// PlaybackSnapshot is everything needed to bring the playback manager back to where it was, e.g. after a restart
+type PlaybackSnapshot struct {
+	Queue         []QueueEntry
+	QueuePosition int
+	State         PlaybackState
+	Elapsed       time.Duration
+	Duration      time.Duration // length of the current track, only informative and not restored
+	Options       PlaybackOptions
+	Volume        int
+	Muted         bool
+}
+
+func (pm *PlaybackManager) GetSnapshot() PlaybackSnapshot {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	status := pm.getStatus()
+	queue := make([]QueueEntry, len(pm.playbackQueue))
+	copy(queue, pm.playbackQueue)
+	return PlaybackSnapshot{
+		Queue:         queue,
+		QueuePosition: status.QueuePosition,
+		State:         status.State,
+		Elapsed:       status.Elapsed,
+		Duration:      status.Duration,
+		Options:       status.Options,
+		Volume:        status.Volume,
+		Muted:         status.Muted,
+	}
+}
+
+// RestoreSnapshot replaces the queue, options and volume with the ones in the snapshot. Entries whose files are
+// gone are left out. A track which was playing is resumed if resume is set, otherwise it is restored paused
+func (pm *PlaybackManager) RestoreSnapshot(snapshot PlaybackSnapshot, resume bool) error {
+	snapshot.Queue = pm.withMetadata(append([]QueueEntry{}, snapshot.Queue...))
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if pm.audioPlayer != nil {
+		err := pm.stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	pm.playbackQueue = []QueueEntry{}
+	pm.QueuePosition = 0
+	currentEntryRestored := false
+	for position, entry := range snapshot.Queue {
+		err := entry.validate()
+		if err != nil {
+			log.Printf("could not restore: %s, error: %v", entry.URI, err)
+			continue
+		}
+		if position < snapshot.QueuePosition {
+			pm.QueuePosition++
+		}
+		currentEntryRestored = currentEntryRestored || position == snapshot.QueuePosition
+		entry.Priority = min(max(entry.Priority, 0), MaximumPriority)
+		pm.playbackQueue = append(pm.playbackQueue, entry)
+		pm.lastQueueEntryId = max(pm.lastQueueEntryId, entry.ID)
+	}
+	pm.options = snapshot.Options
+	pm.randomRoundPlayedIds = map[int]bool{}
+	pm.queueHistory = newQueueHistory(defaultQueueHistorySize)
+	pm.volume, pm.muted = min(max(snapshot.Volume, 0), MaximumVolume), snapshot.Muted
+	pm.volumeChanged()
+	pm.queueModified()
+	pm.notifier.Notify(idle.SubsystemOptions)
+
+	if !currentEntryRestored || snapshot.State == PlaybackStateStopped {
+		return nil
+	}
+	err := pm.createAudioPlayerForCurrentTrack()
+	if err != nil {
+		return err
+	}
+	if snapshot.Elapsed > 0 {
+		err = pm.sequencer.SeekCurrent(snapshot.Elapsed)
+		if err != nil {
+			log.Printf("could not restore the elapsed time, error: %v", err)
+		}
+	}
+	if resume && snapshot.State == PlaybackStatePlaying {
+		return pm.play()
+	}
+	pm.randomRoundPlayedIds[pm.getCurrentEntryId()] = true
+	pm.preloadNextTrack()
+	pm.notifier.Notify(idle.SubsystemPlayer)
+	return nil
+}
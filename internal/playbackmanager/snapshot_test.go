@@ -0,0 +1,41 @@
+package playbackmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestoreSnapshot(t *testing.T) {
+	playbackManager := CreatePlaybackManager()
+	snapshot := PlaybackSnapshot{
+		Queue: []QueueEntry{
+			{ID: 5, URI: "../../music/missing.mp3", Kind: SourceKindFile},
+			{ID: 8, URI: "../../music/sample-3s.mp3", Kind: SourceKindFile},
+			{ID: 9, URI: "../../music/sample-9s.mp3", Kind: SourceKindFile},
+		},
+		QueuePosition: 2,
+		State:         PlaybackStatePlaying,
+		Elapsed:       time.Second * 2,
+		Options:       PlaybackOptions{Repeat: true},
+		Volume:        30,
+	}
+	checkError(playbackManager.RestoreSnapshot(snapshot, false), t)
+	defer playbackManager.Stop()
+
+	status := playbackManager.GetStatus()
+	if status.QueueLength != 2 || status.QueuePosition != 1 || status.CurrentId != 9 {
+		t.Errorf("queue was not restored without the missing file: %+v", status)
+	}
+	if status.State != PlaybackStatePaused || status.Elapsed != time.Second*2 {
+		t.Errorf("expected playback restored paused at the saved time: %+v", status)
+	}
+	if !status.Options.Repeat || status.Volume != 30 {
+		t.Errorf("options and volume were not restored: %+v", status)
+	}
+
+	ids, err := playbackManager.InsertAudioFilesToQueue(0, "../../music/sample-12s.mp3")
+	checkError(err, t)
+	if len(ids) != 1 || ids[0] <= 9 {
+		t.Errorf("new entries must not reuse restored ids, got: %v", ids)
+	}
+}
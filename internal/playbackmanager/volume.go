@@ -0,0 +1,71 @@
+package playbackmanager
+
+import (
+	"fmt"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+)
+
+const (
+	MaximumVolume = 100
+	defaultVolume = MaximumVolume
+)
+
+// SetVolume sets the software volume of the whole output, between 0 and MaximumVolume
+func (pm *PlaybackManager) SetVolume(volume int) error {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	if volume < 0 || volume > MaximumVolume {
+		return fmt.Errorf("invalid volume: %d, expected 0-%d", volume, MaximumVolume)
+	}
+	pm.volume = volume
+	pm.volumeChanged()
+	return nil
+}
+
+// ChangeVolume adds delta to the volume, clamping it to the valid range, and returns the new volume
+func (pm *PlaybackManager) ChangeVolume(delta int) int {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	pm.volume = min(max(pm.volume+delta, 0), MaximumVolume)
+	pm.volumeChanged()
+	return pm.volume
+}
+
+// SetMuted silences the output without forgetting the volume
+func (pm *PlaybackManager) SetMuted(muted bool) {
+	pm.audioPlayerLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	pm.muted = muted
+	pm.volumeChanged()
+}
+
+// SetAudible routes the playback to the speaker or silences it, for a player without any enabled output.
+// A player which is not audible keeps playing like one which is muted, but its tracks do not count as listened to
+func (pm *PlaybackManager) SetAudible(audible bool) {
+	pm.audioPlayerLock.Lock()
+	pm.playbackQueueLock.Lock()
+	defer pm.audioPlayerLock.Unlock()
+	defer pm.playbackQueueLock.Unlock()
+	if pm.audible == audible {
+		return
+	}
+	pm.audible = audible
+	pm.applyGain()
+	pm.publishTrackEvent(EventAudibility)
+}
+
+func (pm *PlaybackManager) volumeChanged() {
+	pm.applyGain()
+	pm.notifier.Notify(idle.SubsystemMixer)
+}
+
+// sets the gain of the output from the volume, mute, the outputs and the fade out of the sleep timer
+func (pm *PlaybackManager) applyGain() {
+	gain := 0.0
+	if !pm.muted && pm.audible {
+		// loudness is perceived roughly logarithmically, a squared curve spreads it more evenly over the range than a linear one
+		gain = float64(pm.volume*pm.volume) / float64(MaximumVolume*MaximumVolume)
+	}
+	pm.volumeMixer.SetGain(gain * pm.sleepFadeGain)
+}
@@ -0,0 +1,352 @@
+package playlist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+	"github.com/arpitpandey992/go-mpd/internal/utils"
+)
+
+const playlistExtension = ".m3u8" // new playlists are written as UTF-8 M3U, plain .m3u files are read as well
+
+// the range of a virtual track is written with the options VLC uses for it, in seconds, on the lines before the entry
+const (
+	startTimeOption = "#EXTVLCOPT:start-time="
+	stopTimeOption  = "#EXTVLCOPT:stop-time="
+)
+
+var (
+	ErrNotFound    = errors.New("no such playlist")
+	ErrExists      = errors.New("playlist already exists")
+	ErrInvalidName = errors.New("invalid playlist name")
+)
+
+// Info describes a stored playlist without reading its entries
+type Info struct {
+	Name         string
+	LastModified time.Time
+}
+
+// entry is a single track of a playlist file, with its location as it is written in the file
+type entry struct {
+	location   string
+	trackRange *playbackmanager.TrackRange // only set for parts of a file, like the tracks of a cue sheet
+}
+
+// Store keeps named playlists as M3U files in a directory, so they can be edited by hand as well.
+// Relative entries are resolved against the scan directories of the audio library
+type Store struct {
+	directory       string
+	scanDirectories []string
+	lock            sync.Mutex
+}
+
+func NewStore(directory string, scanDirectories []string) *Store {
+	return &Store{
+		directory:       directory,
+		scanDirectories: scanDirectories,
+	}
+}
+
+func (store *Store) List() ([]Info, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	dirEntries, err := os.ReadDir(store.directory)
+	if os.IsNotExist(err) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading playlist directory: %w", err)
+	}
+	playlists := []Info{}
+	for _, dirEntry := range dirEntries {
+		extension := filepath.Ext(dirEntry.Name())
+		if dirEntry.IsDir() || !isPlaylistExtension(extension) {
+			continue
+		}
+		fileInfo, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		playlists = append(playlists, Info{Name: strings.TrimSuffix(dirEntry.Name(), extension), LastModified: fileInfo.ModTime()})
+	}
+	sort.Slice(playlists, func(i, j int) bool { return playlists[i].Name < playlists[j].Name })
+	return playlists, nil
+}
+
+// Load returns the tracks in a playlist as queue entries, with relative entries resolved
+func (store *Store) Load(name string) ([]playbackmanager.QueueEntry, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	entries, err := store.readEntries(name)
+	if err != nil {
+		return nil, err
+	}
+	queueEntries := make([]playbackmanager.QueueEntry, len(entries))
+	for i, entry := range entries {
+		uri := store.resolveEntry(entry.location)
+		if entry.trackRange != nil {
+			queueEntries[i] = playbackmanager.NewVirtualQueueEntry(uri, *entry.trackRange)
+		} else {
+			queueEntries[i] = playbackmanager.NewQueueEntry(uri)
+		}
+	}
+	return queueEntries, nil
+}
+
+// Save creates a new playlist with the given queue entries, keeping the range of virtual tracks
+func (store *Store) Save(name string, queueEntries []playbackmanager.QueueEntry) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	_, err := store.findPlaylistFile(name)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	entries := make([]entry, len(queueEntries))
+	for i, queueEntry := range queueEntries {
+		entries[i] = store.getEntry(queueEntry.URI)
+		if queueEntry.Kind == playbackmanager.SourceKindVirtual {
+			entries[i].trackRange = queueEntry.Range
+		}
+	}
+	return store.writeEntries(filepath.Join(store.directory, name+playlistExtension), entries)
+}
+
+func (store *Store) Rename(name, newName string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	playlistFilePath, err := store.findPlaylistFile(name)
+	if err != nil {
+		return err
+	}
+	_, err = store.findPlaylistFile(newName)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrExists, newName)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return os.Rename(playlistFilePath, filepath.Join(store.directory, newName+filepath.Ext(playlistFilePath)))
+}
+
+func (store *Store) Delete(name string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	playlistFilePath, err := store.findPlaylistFile(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(playlistFilePath)
+}
+
+// AddEntry appends a file to a playlist, creating the playlist if it does not exist yet
+func (store *Store) AddEntry(name, filePath string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	playlistFilePath, err := store.findPlaylistFile(name)
+	if errors.Is(err, ErrNotFound) {
+		return store.writeEntries(filepath.Join(store.directory, name+playlistExtension), []entry{store.getEntry(filePath)})
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := readM3u(playlistFilePath)
+	if err != nil {
+		return err
+	}
+	return store.writeEntries(playlistFilePath, append(entries, store.getEntry(filePath)))
+}
+
+// DeleteEntry removes the entry at position from a playlist
+func (store *Store) DeleteEntry(name string, position int) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	playlistFilePath, err := store.findPlaylistFile(name)
+	if err != nil {
+		return err
+	}
+	entries, err := readM3u(playlistFilePath)
+	if err != nil {
+		return err
+	}
+	if position < 0 || position >= len(entries) {
+		return fmt.Errorf("invalid playlist position: %d", position)
+	}
+	return store.writeEntries(playlistFilePath, append(entries[:position], entries[position+1:]...))
+}
+
+// Clear removes every entry from a playlist, creating an empty one if it does not exist yet
+func (store *Store) Clear(name string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	playlistFilePath, err := store.findPlaylistFile(name)
+	if errors.Is(err, ErrNotFound) {
+		playlistFilePath = filepath.Join(store.directory, name+playlistExtension)
+	} else if err != nil {
+		return err
+	}
+	return store.writeEntries(playlistFilePath, []entry{})
+}
+
+func (store *Store) readEntries(name string) ([]entry, error) {
+	playlistFilePath, err := store.findPlaylistFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return readM3u(playlistFilePath)
+}
+
+// returns the path of the file backing a playlist, either a .m3u8 or a .m3u file
+func (store *Store) findPlaylistFile(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	for _, extension := range []string{playlistExtension, ".m3u"} {
+		playlistFilePath := filepath.Join(store.directory, name+extension)
+		exists, err := utils.DoesFileExistsInFileSystem(playlistFilePath)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return playlistFilePath, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// writes the playlist to a temporary file first, so a crash or a full disk never leaves a truncated playlist behind
+func (store *Store) writeEntries(playlistFilePath string, entries []entry) error {
+	err := os.MkdirAll(store.directory, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating playlist directory: %w", err)
+	}
+	content := "#EXTM3U\n"
+	for _, entry := range entries {
+		if entry.trackRange != nil {
+			content += startTimeOption + formatSeconds(entry.trackRange.Start) + "\n"
+			if entry.trackRange.End > 0 {
+				content += stopTimeOption + formatSeconds(entry.trackRange.End) + "\n"
+			}
+		}
+		content += entry.location + "\n"
+	}
+	temporaryFilePath := playlistFilePath + ".tmp"
+	err = os.WriteFile(temporaryFilePath, []byte(content), 0644)
+	if err != nil {
+		os.Remove(temporaryFilePath)
+		return fmt.Errorf("error writing playlist file: %w", err)
+	}
+	return os.Rename(temporaryFilePath, playlistFilePath)
+}
+
+// files inside a scan directory are stored relative to it, which keeps playlists valid when the library is moved.
+// URLs of streams are stored as they are
+func (store *Store) getEntry(filePath string) entry {
+	if isURL(filePath) {
+		return entry{location: filePath}
+	}
+	absolutePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return entry{location: filePath}
+	}
+	for _, scanDirectory := range store.scanDirectories {
+		// scan directories are usually configured relative to the working directory, e.g. ./music
+		scanDirectory, err := filepath.Abs(scanDirectory)
+		if err != nil {
+			continue
+		}
+		relativePath, err := filepath.Rel(scanDirectory, absolutePath)
+		if err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+			return entry{location: relativePath}
+		}
+	}
+	return entry{location: absolutePath}
+}
+
+// resolves a relative entry against the scan directories, and then the playlist directory as M3U players usually do
+func (store *Store) resolveEntry(entry string) string {
+	if filepath.IsAbs(entry) || isURL(entry) {
+		return entry
+	}
+	directories := append(append([]string{}, store.scanDirectories...), store.directory)
+	for _, directory := range directories {
+		filePath := filepath.Join(directory, entry)
+		if exists, _ := utils.DoesFileExistsInFileSystem(filePath); exists {
+			return filePath
+		}
+	}
+	return filepath.Join(store.directory, entry)
+}
+
+// reads the entries of an M3U file with the range options before them, skipping blank lines as well as comments
+// and other extended M3U directives
+func readM3u(playlistFilePath string) ([]entry, error) {
+	file, err := os.Open(playlistFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading playlist: %w", err)
+	}
+	defer file.Close()
+	entries := []entry{}
+	var trackRange *playbackmanager.TrackRange
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, startTimeOption) || strings.HasPrefix(line, stopTimeOption):
+			if trackRange == nil {
+				trackRange = &playbackmanager.TrackRange{}
+			}
+			err := parseRangeOption(line, trackRange)
+			if err != nil {
+				return nil, fmt.Errorf("error reading playlist %s: %w", playlistFilePath, err)
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			entries = append(entries, entry{location: line, trackRange: trackRange})
+			trackRange = nil
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func parseRangeOption(line string, trackRange *playbackmanager.TrackRange) error {
+	option, value, _ := strings.Cut(strings.TrimPrefix(line, "#EXTVLCOPT:"), "=")
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return fmt.Errorf("invalid %s: %s", option, value)
+	}
+	duration := time.Duration(math.Round(seconds * float64(time.Second)))
+	if option == "start-time" {
+		trackRange.Start = duration
+	} else {
+		trackRange.End = duration
+	}
+	return nil
+}
+
+func formatSeconds(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', -1, 64)
+}
+
+func isURL(entry string) bool {
+	return strings.Contains(entry, "://")
+}
+
+func isPlaylistExtension(extension string) bool {
+	extension = strings.ToLower(extension)
+	return extension == playlistExtension || extension == ".m3u"
+}
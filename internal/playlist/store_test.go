@@ -0,0 +1,124 @@
+package playlist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+func TestStore(t *testing.T) {
+	libraryDirectory := t.TempDir()
+	playlistDirectory := filepath.Join(t.TempDir(), "playlists")
+	store := NewStore(playlistDirectory, []string{libraryDirectory})
+	firstTrack := filepath.Join(libraryDirectory, "album", "01.flac")
+	secondTrack := "/elsewhere/02.mp3"
+
+	checkError(store.Save("mix", []playbackmanager.QueueEntry{playbackmanager.NewQueueEntry(firstTrack), playbackmanager.NewQueueEntry(secondTrack)}), t)
+	if err := store.Save("mix", nil); !errors.Is(err, ErrExists) {
+		t.Errorf("expected saving over an existing playlist to fail, got: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(playlistDirectory, "mix.m3u8"))
+	checkError(err, t)
+	if string(content) != "#EXTM3U\nalbum/01.flac\n/elsewhere/02.mp3\n" {
+		t.Errorf("unexpected playlist file: %q", content)
+	}
+
+	checkError(store.AddEntry("mix", secondTrack), t)
+	checkError(store.DeleteEntry("mix", 1), t)
+	checkError(store.Rename("mix", "renamed"), t)
+	entries, err := store.Load("renamed")
+	checkError(err, t)
+	expected := []playbackmanager.QueueEntry{playbackmanager.NewQueueEntry(filepath.Join(playlistDirectory, "album/01.flac")), playbackmanager.NewQueueEntry(secondTrack)}
+	if !reflect.DeepEqual(entries, expected) {
+		// the first track does not exist, so it is resolved against the playlist directory
+		t.Errorf("expected: %v, got: %v", expected, entries)
+	}
+
+	playlists, err := store.List()
+	checkError(err, t)
+	if len(playlists) != 1 || playlists[0].Name != "renamed" {
+		t.Errorf("unexpected playlists: %+v", playlists)
+	}
+	checkError(store.Delete("renamed"), t)
+	if _, err := store.Load("renamed"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a deleted playlist to be gone, got: %v", err)
+	}
+	if err := store.Save("../escape", nil); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("expected names with path separators to be rejected, got: %v", err)
+	}
+}
+
+func TestRelativeScanDirectory(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	checkError(err, t)
+	libraryDirectory, err := filepath.Rel(workingDirectory, t.TempDir())
+	checkError(err, t)
+	playlistDirectory := t.TempDir()
+	store := NewStore(playlistDirectory, []string{libraryDirectory})
+
+	checkError(store.Save("live", []playbackmanager.QueueEntry{playbackmanager.NewQueueEntry(filepath.Join(libraryDirectory, "..Live", "01.flac"))}), t)
+	content, err := os.ReadFile(filepath.Join(playlistDirectory, "live.m3u8"))
+	checkError(err, t)
+	if string(content) != "#EXTM3U\n..Live/01.flac\n" {
+		t.Errorf("expected the entry relative to the scan directory, got: %q", content)
+	}
+}
+
+func TestResolveHandWrittenPlaylist(t *testing.T) {
+	libraryDirectory := t.TempDir()
+	playlistDirectory := t.TempDir()
+	track := filepath.Join(libraryDirectory, "track.mp3")
+	checkError(os.WriteFile(track, []byte{}, 0644), t)
+	content := "#EXTM3U\n#EXTINF:123,Artist - Title\ntrack.mp3\n\n"
+	checkError(os.WriteFile(filepath.Join(playlistDirectory, "by hand.m3u"), []byte(content), 0644), t)
+
+	entries, err := NewStore(playlistDirectory, []string{libraryDirectory}).Load("by hand")
+	checkError(err, t)
+	if !reflect.DeepEqual(entries, []playbackmanager.QueueEntry{playbackmanager.NewQueueEntry(track)}) {
+		t.Errorf("expected the entry resolved against the scan directory, got: %v", entries)
+	}
+}
+
+func TestRangesRoundTrip(t *testing.T) {
+	libraryDirectory := t.TempDir()
+	playlistDirectory := t.TempDir()
+	store := NewStore(playlistDirectory, []string{libraryDirectory})
+	cueFile := filepath.Join(libraryDirectory, "album.flac")
+	checkError(os.WriteFile(cueFile, []byte{}, 0644), t)
+	saved := []playbackmanager.QueueEntry{
+		playbackmanager.NewVirtualQueueEntry(cueFile, playbackmanager.TrackRange{Start: time.Minute + time.Millisecond*250, End: time.Minute * 4}),
+		playbackmanager.NewVirtualQueueEntry(cueFile, playbackmanager.TrackRange{Start: time.Minute * 4}),
+		playbackmanager.NewQueueEntry("https://radio.example.com/live"),
+		playbackmanager.NewQueueEntry(cueFile),
+	}
+
+	checkError(store.Save("cue", saved), t)
+	content, err := os.ReadFile(filepath.Join(playlistDirectory, "cue.m3u8"))
+	checkError(err, t)
+	expectedContent := "#EXTM3U\n#EXTVLCOPT:start-time=60.25\n#EXTVLCOPT:stop-time=240\nalbum.flac\n" +
+		"#EXTVLCOPT:start-time=240\nalbum.flac\nhttps://radio.example.com/live\nalbum.flac\n"
+	if string(content) != expectedContent {
+		t.Errorf("unexpected playlist file: %q", content)
+	}
+
+	// removing an entry keeps the ranges of the others
+	checkError(store.AddEntry("cue", cueFile), t)
+	checkError(store.DeleteEntry("cue", 4), t)
+	loaded, err := store.Load("cue")
+	checkError(err, t)
+	if !reflect.DeepEqual(loaded, saved) {
+		t.Errorf("expected: %+v, got: %+v", saved, loaded)
+	}
+}
+
+func checkError(err error, t *testing.T) {
+	t.Helper()
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,113 @@
+package replaygain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Mode selects which of the ReplayGain values of a track is applied
+type Mode string
+
+const (
+	ModeOff   Mode = "off"
+	ModeTrack Mode = "track"
+	ModeAlbum Mode = "album"
+	ModeAuto  Mode = "auto" // album gain while the queue is played in order, track gain while it is shuffled
+)
+
+var AllModes = []Mode{ModeOff, ModeTrack, ModeAlbum, ModeAuto}
+
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range AllModes {
+		if string(mode) == strings.ToLower(name) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized replay gain mode: %s", name)
+}
+
+// Info holds the ReplayGain values of a track, gains are in dB and peaks relative to full scale
+type Info struct {
+	TrackGain    float64
+	TrackPeak    float64
+	HasTrackGain bool
+	AlbumGain    float64
+	AlbumPeak    float64
+	HasAlbumGain bool
+}
+
+// Settings decide how the ReplayGain values of a track are applied
+type Settings struct {
+	Mode            Mode
+	Preamp          float64 // dB added on top of the ReplayGain of every track
+	PreventClipping bool    // lowers the gain so that the peak of a track never exceeds full scale
+}
+
+// ParseTags picks the ReplayGain values out of tags, whose names are matched case insensitively.
+// Values which can not be parsed are ignored
+func ParseTags(tags map[string][]string) Info {
+	info := Info{}
+	for name, values := range tags {
+		if len(values) == 0 {
+			continue
+		}
+		value := values[0]
+		switch strings.ToUpper(name) {
+		case "REPLAYGAIN_TRACK_GAIN":
+			info.TrackGain, info.HasTrackGain = parseGain(value)
+		case "REPLAYGAIN_TRACK_PEAK":
+			info.TrackPeak, _ = parsePeak(value)
+		case "REPLAYGAIN_ALBUM_GAIN":
+			info.AlbumGain, info.HasAlbumGain = parseGain(value)
+		case "REPLAYGAIN_ALBUM_PEAK":
+			info.AlbumPeak, _ = parsePeak(value)
+		}
+	}
+	return info
+}
+
+// GetScale returns the factor by which the samples of a track are multiplied. shuffled tells
+// whether the queue is played in a random order, which ModeAuto depends upon
+func (settings Settings) GetScale(info Info, shuffled bool) float64 {
+	mode := settings.Mode
+	if mode == ModeAuto {
+		mode = ModeAlbum
+		if shuffled {
+			mode = ModeTrack
+		}
+	}
+	var gain, peak float64
+	switch {
+	case mode == ModeOff:
+		return 1
+	case mode == ModeAlbum && info.HasAlbumGain, mode == ModeTrack && !info.HasTrackGain && info.HasAlbumGain:
+		gain, peak = info.AlbumGain, info.AlbumPeak
+	case info.HasTrackGain:
+		gain, peak = info.TrackGain, info.TrackPeak
+	default:
+		// the track was never scanned, leave it as it is
+		return 1
+	}
+	scale := math.Pow(10, (gain+settings.Preamp)/20)
+	if settings.PreventClipping && peak > 0 && scale*peak > 1 {
+		scale = 1 / peak
+	}
+	return scale
+}
+
+// parses gains like "-6.48 dB"
+func parseGain(value string) (float64, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) > 2 && strings.EqualFold(value[len(value)-2:], "db") {
+		value = strings.TrimSpace(value[:len(value)-2])
+	}
+	gain, err := strconv.ParseFloat(value, 64)
+	return gain, err == nil
+}
+
+func parsePeak(value string) (float64, bool) {
+	peak, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return peak, err == nil && peak >= 0
+}
@@ -0,0 +1,37 @@
+package replaygain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGetScale(t *testing.T) {
+	info := ParseTags(map[string][]string{
+		"replaygain_track_gain": {"-6.02 dB"},
+		"REPLAYGAIN_TRACK_PEAK": {"0.5"},
+		"REPLAYGAIN_ALBUM_GAIN": {"+6.02 dB"},
+		"REPLAYGAIN_ALBUM_PEAK": {"0.8"},
+	})
+	testCases := []struct {
+		settings Settings
+		info     Info
+		shuffled bool
+		expected float64
+	}{
+		{Settings{Mode: ModeOff}, info, false, 1},
+		{Settings{Mode: ModeTrack}, info, false, 0.5},
+		{Settings{Mode: ModeTrack, Preamp: 6.02}, info, false, 1},
+		{Settings{Mode: ModeAlbum}, info, false, 2},
+		{Settings{Mode: ModeAlbum, PreventClipping: true}, info, false, 1.25},
+		{Settings{Mode: ModeAuto}, info, false, 2},
+		{Settings{Mode: ModeAuto}, info, true, 0.5},
+		{Settings{Mode: ModeAlbum}, Info{TrackGain: -6.02, HasTrackGain: true}, false, 0.5},
+		{Settings{Mode: ModeTrack}, Info{}, false, 1},
+	}
+	for _, testCase := range testCases {
+		scale := testCase.settings.GetScale(testCase.info, testCase.shuffled)
+		if math.Abs(scale-testCase.expected) > 0.001 {
+			t.Errorf("%+v, shuffled: %v: expected %v, got %v", testCase.settings, testCase.shuffled, testCase.expected, scale)
+		}
+	}
+}
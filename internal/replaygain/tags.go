@@ -0,0 +1,158 @@
+package replaygain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/meta"
+)
+
+// ReadFile reads the ReplayGain values from the tags of an audio file: Vorbis comments of FLAC files and
+// ID3v2 user defined text frames (TXXX) of MP3 files. A file without such tags returns an empty Info
+func ReadFile(filePath string) (Info, error) {
+	var tags map[string][]string
+	var err error
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".flac":
+		tags, err = readVorbisComments(filePath)
+	case ".mp3":
+		tags, err = readId3v2UserTextFrames(filePath)
+	default:
+		return Info{}, fmt.Errorf("reading tags is not supported for: %s", filePath)
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	return ParseTags(tags), nil
+}
+
+func readVorbisComments(filePath string) (map[string][]string, error) {
+	stream, err := flac.ParseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	tags := map[string][]string{}
+	for _, block := range stream.Blocks {
+		if comment, ok := block.Body.(*meta.VorbisComment); ok {
+			for _, tag := range comment.Tags {
+				tags[tag[0]] = append(tags[tag[0]], tag[1])
+			}
+		}
+	}
+	return tags, nil
+}
+
+// reads the TXXX frames of an ID3v2.3 or ID3v2.4 tag at the start of the file, keyed by their description
+func readId3v2UserTextFrames(filePath string) (map[string][]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+
+	header := make([]byte, 10)
+	_, err = io.ReadFull(reader, header)
+	if err != nil || string(header[:3]) != "ID3" {
+		// no ID3v2 tag, so there are no tags to read
+		return map[string][]string{}, nil
+	}
+	version, flags := header[3], header[5]
+	if version != 3 && version != 4 {
+		return nil, fmt.Errorf("unsupported ID3v2 version: 2.%d", version)
+	}
+	tag := make([]byte, decodeSyncsafeInteger(header[6:10]))
+	_, err = io.ReadFull(reader, tag)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ID3v2 tag: %w", err)
+	}
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		// skip the extended header, its size excludes itself in ID3v2.3 and includes itself in ID3v2.4
+		extendedHeaderSize := int(binary.BigEndian.Uint32(tag[:4])) + 4
+		if version == 4 {
+			extendedHeaderSize = decodeSyncsafeInteger(tag[:4])
+		}
+		tag = tag[min(extendedHeaderSize, len(tag)):]
+	}
+
+	tags := map[string][]string{}
+	for len(tag) >= 10 && tag[0] != 0 {
+		frameId := string(tag[:4])
+		frameSize := int(binary.BigEndian.Uint32(tag[4:8]))
+		if version == 4 {
+			frameSize = decodeSyncsafeInteger(tag[4:8])
+		}
+		frameFlags := tag[9]
+		if frameSize > len(tag)-10 {
+			break
+		}
+		frame := tag[10 : 10+frameSize]
+		tag = tag[10+frameSize:]
+		// compressed and encrypted frames are not supported
+		if frameId != "TXXX" || frameFlags&0xC0 != 0 || len(frame) == 0 {
+			continue
+		}
+		description, value := splitUserTextFrame(frame)
+		tags[description] = append(tags[description], value)
+	}
+	return tags, nil
+}
+
+// splits the content of a TXXX frame into its description and its value
+func splitUserTextFrame(frame []byte) (description, value string) {
+	encoding, text := frame[0], frame[1:]
+	terminator := []byte{0}
+	if encoding == 1 || encoding == 2 {
+		terminator = []byte{0, 0}
+	}
+	for i := 0; i+len(terminator) <= len(text); i += len(terminator) {
+		if bytes.Equal(text[i:i+len(terminator)], terminator) {
+			return decodeId3v2Text(encoding, text[:i]), decodeId3v2Text(encoding, text[i+len(terminator):])
+		}
+	}
+	return decodeId3v2Text(encoding, text), ""
+}
+
+func decodeId3v2Text(encoding byte, text []byte) string {
+	switch encoding {
+	case 0:
+		// ISO-8859-1 maps directly onto the first unicode code points
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		return strings.TrimRight(string(runes), "\x00")
+	case 1, 2:
+		var byteOrder binary.ByteOrder = binary.BigEndian
+		if encoding == 1 && len(text) >= 2 {
+			if text[0] == 0xFF && text[1] == 0xFE {
+				byteOrder = binary.LittleEndian
+			}
+			text = text[2:]
+		}
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			units[i] = byteOrder.Uint16(text[i*2:])
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	default:
+		return strings.TrimRight(string(text), "\x00")
+	}
+}
+
+func decodeSyncsafeInteger(data []byte) int {
+	value := 0
+	for _, b := range data {
+		value = value<<7 | int(b&0x7F)
+	}
+	return value
+}
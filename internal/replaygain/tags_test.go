@@ -0,0 +1,73 @@
+package replaygain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+func getId3v2Frame(id string, content []byte, syncsafe bool) []byte {
+	size := len(content)
+	frame := []byte(id)
+	if syncsafe {
+		frame = append(frame, byte(size>>21&0x7F), byte(size>>14&0x7F), byte(size>>7&0x7F), byte(size&0x7F))
+	} else {
+		frame = append(frame, byte(size>>24), byte(size>>16), byte(size>>8), byte(size))
+	}
+	frame = append(frame, 0, 0)
+	return append(frame, content...)
+}
+
+func writeId3v2Tag(t *testing.T, version byte, frames ...[]byte) string {
+	tag := []byte{}
+	for _, frame := range frames {
+		tag = append(tag, frame...)
+	}
+	size := len(tag)
+	header := []byte{'I', 'D', '3', version, 0, 0, byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	filePath := filepath.Join(t.TempDir(), "track.mp3")
+	err := os.WriteFile(filePath, append(append(header, tag...), 0xFF, 0xFB), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestReadId3v2ReplayGain(t *testing.T) {
+	latin1Frame := append([]byte{0}, []byte("REPLAYGAIN_TRACK_GAIN\x00-3.50 dB")...)
+	utf16Text := []byte{1, 0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune("replaygain_album_gain")) {
+		utf16Text = append(utf16Text, byte(unit), byte(unit>>8))
+	}
+	utf16Text = append(utf16Text, 0, 0, 0xFF, 0xFE)
+	for _, unit := range utf16.Encode([]rune("1.25 dB")) {
+		utf16Text = append(utf16Text, byte(unit), byte(unit>>8))
+	}
+
+	for _, version := range []byte{3, 4} {
+		syncsafe := version == 4
+		filePath := writeId3v2Tag(t, version,
+			getId3v2Frame("TIT2", append([]byte{3}, []byte("Title")...), syncsafe),
+			getId3v2Frame("TXXX", latin1Frame, syncsafe),
+			getId3v2Frame("TXXX", utf16Text, syncsafe),
+		)
+		info, err := ReadFile(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.HasTrackGain || info.TrackGain != -3.5 || !info.HasAlbumGain || info.AlbumGain != 1.25 {
+			t.Errorf("ID3v2.%d: unexpected replay gain: %+v", version, info)
+		}
+	}
+}
+
+func TestReadFileWithoutTags(t *testing.T) {
+	info, err := ReadFile("../../music/sample-3s.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.HasTrackGain || info.HasAlbumGain {
+		t.Errorf("expected no replay gain, got: %+v", info)
+	}
+}
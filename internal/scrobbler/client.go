@@ -0,0 +1,142 @@
+package scrobbler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/history"
+)
+
+const (
+	DefaultEndpoint = "https://api.listenbrainz.org"
+
+	requestTimeout   = 10 * time.Second
+	submissionClient = "go-mpd"
+)
+
+// Client submits listens to ListenBrainz or to any other service offering the same API
+type Client struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// SubmissionError is returned for a request which the API refused
+type SubmissionError struct {
+	StatusCode int
+	Message    string
+}
+
+func (err *SubmissionError) Error() string {
+	return fmt.Sprintf("listen submission failed with status %d: %s", err.StatusCode, err.Message)
+}
+
+// retryable reports whether submitting the same listens later may succeed
+func (err *SubmissionError) retryable() bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+}
+
+// unauthorized reports whether the token was refused, which no listen can be submitted with
+func (err *SubmissionError) unauthorized() bool {
+	return err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden
+}
+
+func NewClient(endpoint, token string) *Client {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+type submission struct {
+	ListenType string   `json:"listen_type"` // "single", "import" for several listens or "playing_now"
+	Payload    []listen `json:"payload"`
+}
+
+type listen struct {
+	ListenedAt    int64         `json:"listened_at,omitempty"`
+	TrackMetadata trackMetadata `json:"track_metadata"`
+}
+
+type trackMetadata struct {
+	ArtistName     string         `json:"artist_name"`
+	TrackName      string         `json:"track_name"`
+	ReleaseName    string         `json:"release_name,omitempty"`
+	AdditionalInfo additionalInfo `json:"additional_info"`
+}
+
+type additionalInfo struct {
+	DurationMs       int64  `json:"duration_ms,omitempty"`
+	TrackNumber      int    `json:"tracknumber,omitempty"`
+	SubmissionClient string `json:"submission_client"`
+}
+
+// SubmitPlayingNow tells the service which track just started playing
+func (client *Client) SubmitPlayingNow(record history.Record) error {
+	payload := newListen(record)
+	payload.ListenedAt = 0
+	return client.submit(submission{ListenType: "playing_now", Payload: []listen{payload}})
+}
+
+// SubmitListens submits the listens in a single request
+func (client *Client) SubmitListens(records []history.Record) error {
+	listenType := "import"
+	if len(records) == 1 {
+		listenType = "single"
+	}
+	payload := []listen{}
+	for _, record := range records {
+		payload = append(payload, newListen(record))
+	}
+	return client.submit(submission{ListenType: listenType, Payload: payload})
+}
+
+func (client *Client) submit(body submission) error {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error marshalling listens: %w", err)
+	}
+	request, err := http.NewRequest(http.MethodPost, client.endpoint+"/1/submit-listens", bytes.NewReader(requestBody))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Token "+client.token)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return &SubmissionError{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	return nil
+}
+
+func newListen(record history.Record) listen {
+	metadata := trackMetadata{
+		ArtistName:     strings.Join(record.Artist, ", "),
+		TrackName:      strings.Join(record.Title, ", "),
+		ReleaseName:    strings.Join(record.Album, ", "),
+		AdditionalInfo: additionalInfo{DurationMs: record.Duration.Milliseconds(), SubmissionClient: submissionClient},
+	}
+	if record.TrackNumber != nil {
+		metadata.AdditionalInfo.TrackNumber = *record.TrackNumber
+	}
+	return listen{ListenedAt: record.PlayedAt.Unix(), TrackMetadata: metadata}
+}
+
+// isSubmittable reports whether the record has the artist and title which every listen needs
+func isSubmittable(record history.Record) bool {
+	return len(record.Artist) > 0 && len(record.Title) > 0
+}
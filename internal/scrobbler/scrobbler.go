@@ -0,0 +1,283 @@
+package scrobbler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/history"
+)
+
+const (
+	DefaultRetryInterval = time.Minute
+	maximumBatchSize     = 100 // listens submitted in a single request when catching up
+	maximumRefusedDelay  = time.Hour
+)
+
+// Scrobbler submits listens and now playing notifications in the background, so a slow or unreachable
+// service never holds up the caller. Listens are kept in a queue file until they were submitted,
+// and the ones made while offline are submitted in batches once the service can be reached again.
+// Listens are also kept while the service refuses the token, which is tried again less and less often
+type Scrobbler struct {
+	client        *Client
+	queueFilePath string // empty to keep the queue in memory only
+	retryInterval time.Duration
+
+	// nothing is submitted before refusedUntil once the token was refused, the delay doubles with every refusal.
+	// Only used by the run goroutine
+	refusedUntil time.Time
+	refusedDelay time.Duration
+
+	pending     []history.Record
+	pendingLock sync.Mutex
+
+	nowPlaying chan history.Record // holds only the latest track, older ones are outdated anyway
+	wakeup     chan struct{}
+	done       chan struct{}
+	stopped    chan struct{}
+}
+
+// NewScrobbler loads the listens left in the queue file and starts submitting them
+func NewScrobbler(client *Client, queueFilePath string, retryInterval time.Duration) *Scrobbler {
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+	scrobbler := &Scrobbler{
+		client:        client,
+		queueFilePath: queueFilePath,
+		retryInterval: retryInterval,
+		pending:       []history.Record{},
+		nowPlaying:    make(chan history.Record, 1),
+		wakeup:        make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	if queueFilePath != "" {
+		pending, err := loadQueue(queueFilePath)
+		if err != nil {
+			log.Printf("could not load the scrobble queue, error: %v", err)
+		}
+		scrobbler.pending = append(scrobbler.pending, pending...)
+	}
+	go scrobbler.run()
+	scrobbler.wake()
+	return scrobbler
+}
+
+// NowPlaying announces the track which just started playing
+func (scrobbler *Scrobbler) NowPlaying(record history.Record) {
+	if !isSubmittable(record) {
+		return
+	}
+	select {
+	case <-scrobbler.nowPlaying:
+	default:
+	}
+	select {
+	case scrobbler.nowPlaying <- record:
+	default:
+	}
+}
+
+// Scrobble queues a listen for submission
+func (scrobbler *Scrobbler) Scrobble(record history.Record) {
+	if !isSubmittable(record) {
+		log.Printf("not scrobbling %s, its artist or title is unknown", record.FilePath)
+		return
+	}
+	scrobbler.pendingLock.Lock()
+	scrobbler.pending = append(scrobbler.pending, record)
+	if scrobbler.queueFilePath != "" {
+		err := appendToQueue(scrobbler.queueFilePath, record)
+		if err != nil {
+			log.Printf("could not save the scrobble queue, error: %v", err)
+		}
+	}
+	scrobbler.pendingLock.Unlock()
+	scrobbler.wake()
+}
+
+// Pending returns how many listens are waiting to be submitted
+func (scrobbler *Scrobbler) Pending() int {
+	scrobbler.pendingLock.Lock()
+	defer scrobbler.pendingLock.Unlock()
+	return len(scrobbler.pending)
+}
+
+// Close stops submitting, the listens which are still pending stay in the queue file
+func (scrobbler *Scrobbler) Close() {
+	close(scrobbler.done)
+	<-scrobbler.stopped
+}
+
+func (scrobbler *Scrobbler) wake() {
+	select {
+	case scrobbler.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (scrobbler *Scrobbler) run() {
+	defer close(scrobbler.stopped)
+	ticker := time.NewTicker(scrobbler.retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-scrobbler.done:
+			return
+		case record := <-scrobbler.nowPlaying:
+			if scrobbler.isRefused() {
+				continue
+			}
+			err := scrobbler.client.SubmitPlayingNow(record)
+			if err != nil {
+				log.Printf("could not submit now playing, error: %v", err)
+			}
+		case <-scrobbler.wakeup:
+			scrobbler.submitPending()
+		case <-ticker.C:
+			scrobbler.submitPending()
+		}
+	}
+}
+
+// submits the pending listens oldest first, until they are all submitted or the service can not be reached
+func (scrobbler *Scrobbler) submitPending() {
+	for !scrobbler.isRefused() {
+		scrobbler.pendingLock.Lock()
+		batch := append([]history.Record{}, scrobbler.pending[:min(len(scrobbler.pending), maximumBatchSize)]...)
+		scrobbler.pendingLock.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+		handled := scrobbler.submitBatch(batch)
+		if handled > 0 {
+			// the queue file is only rewritten here, listens made in the meantime were appended to it after the batch
+			scrobbler.pendingLock.Lock()
+			scrobbler.pending = scrobbler.pending[handled:]
+			scrobbler.saveQueue()
+			scrobbler.pendingLock.Unlock()
+		}
+		if handled < len(batch) {
+			return
+		}
+	}
+}
+
+// submits a batch of listens and returns how many of them, from the start of the batch, were either submitted or refused.
+// A refused batch is split in halves until the listens which the service refuses are found, only these are dropped
+func (scrobbler *Scrobbler) submitBatch(batch []history.Record) (handled int) {
+	err := scrobbler.client.SubmitListens(batch)
+	if err == nil {
+		scrobbler.refusedDelay = 0
+		return len(batch)
+	}
+	var submissionError *SubmissionError
+	switch {
+	case !errors.As(err, &submissionError) || submissionError.retryable():
+		log.Printf("could not submit %d listens, retrying in %v, error: %v", len(batch), scrobbler.retryInterval, err)
+		return 0
+	case submissionError.unauthorized():
+		// the refusal may only be a passing problem of the service or of a proxy in between
+		scrobbler.refusedDelay = min(max(scrobbler.refusedDelay*2, scrobbler.retryInterval), maximumRefusedDelay)
+		scrobbler.refusedUntil = time.Now().Add(scrobbler.refusedDelay)
+		log.Printf("the token was refused, keeping the listens and trying again in %v, error: %v", scrobbler.refusedDelay, err)
+		return 0
+	case len(batch) == 1:
+		log.Printf("dropping the listen of %s refused by the service, error: %v", batch[0].FilePath, err)
+		return 1
+	}
+	half := len(batch) / 2
+	handled = scrobbler.submitBatch(batch[:half])
+	if handled < half {
+		return handled
+	}
+	return half + scrobbler.submitBatch(batch[half:])
+}
+
+func (scrobbler *Scrobbler) isRefused() bool {
+	return time.Now().Before(scrobbler.refusedUntil)
+}
+
+// writes the pending listens to the queue file, must be called with pendingLock held
+func (scrobbler *Scrobbler) saveQueue() {
+	if scrobbler.queueFilePath == "" {
+		return
+	}
+	err := saveQueue(scrobbler.queueFilePath, scrobbler.pending)
+	if err != nil {
+		log.Printf("could not save the scrobble queue, error: %v", err)
+	}
+}
+
+func loadQueue(filePath string) ([]history.Record, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening scrobble queue: %w", err)
+	}
+	defer file.Close()
+	records := []history.Record{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record history.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("skipping invalid scrobble queue entry, error: %v", err)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// appends a listen to the queue file, which is only rewritten as a whole once listens were submitted
+func appendToQueue(filePath string, record history.Record) error {
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating scrobble queue directory: %w", err)
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshalling listen: %w", err)
+	}
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening scrobble queue: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("error writing scrobble queue: %w", err)
+	}
+	return nil
+}
+
+// the queue is written to a temporary file first, so a crash never loses the listens which were already queued
+func saveQueue(filePath string, records []history.Record) error {
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating scrobble queue directory: %w", err)
+	}
+	fileContent := []byte{}
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("error marshalling listen: %w", err)
+		}
+		fileContent = append(append(fileContent, line...), '\n')
+	}
+	temporaryFilePath := filePath + ".tmp"
+	err = os.WriteFile(temporaryFilePath, fileContent, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing scrobble queue: %w", err)
+	}
+	return os.Rename(temporaryFilePath, filePath)
+}
@@ -0,0 +1,179 @@
+package scrobbler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/history"
+)
+
+// stands in for the ListenBrainz API, failing with the status in failWith as long as it is set,
+// and refusing every submission which contains a listen of the track named rejectTitle
+type fakeListenBrainz struct {
+	lock        sync.Mutex
+	submissions []submission
+	failWith    int
+	rejectTitle string
+}
+
+func (fake *fakeListenBrainz) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	if request.URL.Path != "/1/submit-listens" || request.Header.Get("Authorization") != "Token secret" {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if fake.failWith != 0 {
+		writer.WriteHeader(fake.failWith)
+		return
+	}
+	var body submission
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, listen := range body.Payload {
+		if listen.TrackMetadata.TrackName == fake.rejectTitle {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	fake.submissions = append(fake.submissions, body)
+}
+
+func (fake *fakeListenBrainz) getSubmissions() []submission {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	return append([]submission{}, fake.submissions...)
+}
+
+func (fake *fakeListenBrainz) setFailWith(status int) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.failWith = status
+}
+
+func (fake *fakeListenBrainz) setRejectTitle(title string) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.rejectTitle = title
+}
+
+func newRecord(title string, playedAt time.Time) history.Record {
+	return history.Record{PlayedAt: playedAt, FilePath: title + ".mp3", Title: []string{title}, Artist: []string{"Artist"}, Duration: time.Minute * 3}
+}
+
+func waitFor(condition func() bool, t *testing.T) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScrobbler(t *testing.T) {
+	fake := &fakeListenBrainz{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	queueFilePath := filepath.Join(t.TempDir(), "scrobbles.jsonl")
+	playedAt := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+
+	scrobbler := NewScrobbler(NewClient(server.URL+"/", "secret"), queueFilePath, 50*time.Millisecond)
+	scrobbler.NowPlaying(newRecord("first", playedAt))
+	scrobbler.Scrobble(newRecord("first", playedAt))
+	scrobbler.Scrobble(history.Record{FilePath: "untagged.mp3"})
+	waitFor(func() bool { return len(fake.getSubmissions()) == 2 }, t)
+	submissions := fake.getSubmissions()
+	if submissions[0].ListenType != "playing_now" {
+		// now playing and the listen are submitted in no particular order
+		submissions[0], submissions[1] = submissions[1], submissions[0]
+	}
+	if submissions[0].ListenType != "playing_now" || submissions[0].Payload[0].ListenedAt != 0 {
+		t.Errorf("unexpected now playing submission: %+v", submissions[0])
+	}
+	listen := submissions[1].Payload[0]
+	if submissions[1].ListenType != "single" || listen.ListenedAt != playedAt.Unix() || listen.TrackMetadata.TrackName != "first" ||
+		listen.TrackMetadata.AdditionalInfo.DurationMs != 180000 {
+		t.Errorf("unexpected listen submission: %+v", submissions[1])
+	}
+
+	// listens made while the service is down are kept across restarts and submitted together later on
+	fake.setFailWith(http.StatusServiceUnavailable)
+	scrobbler.Scrobble(newRecord("second", playedAt.Add(time.Hour)))
+	scrobbler.Scrobble(newRecord("third", playedAt.Add(time.Hour*2)))
+	if records, err := loadQueue(queueFilePath); err != nil || len(records) != 2 {
+		t.Fatalf("expected the listens to be appended to the queue file, got: %+v, error: %v", records, err)
+	}
+	scrobbler.Close()
+	if records, err := loadQueue(queueFilePath); err != nil || len(records) != 2 {
+		t.Fatalf("expected 2 queued listens, got: %+v, error: %v", records, err)
+	}
+	fake.setFailWith(0)
+	scrobbler = NewScrobbler(NewClient(server.URL, "secret"), queueFilePath, 50*time.Millisecond)
+	defer scrobbler.Close()
+	waitFor(func() bool { return scrobbler.Pending() == 0 }, t)
+	submissions = fake.getSubmissions()
+	if last := submissions[len(submissions)-1]; last.ListenType != "import" || len(last.Payload) != 2 {
+		t.Errorf("expected the queued listens in a single batch, got: %+v", last)
+	}
+
+	// refused listens are dropped instead of blocking the queue
+	fake.setFailWith(http.StatusBadRequest)
+	scrobbler.Scrobble(newRecord("fourth", playedAt.Add(time.Hour*3)))
+	waitFor(func() bool { return scrobbler.Pending() == 0 }, t)
+	if records, err := loadQueue(queueFilePath); err != nil || len(records) != 0 {
+		t.Errorf("expected the queue file to be compacted, got: %+v, error: %v", records, err)
+	}
+}
+
+func TestScrobblerRefusals(t *testing.T) {
+	fake := &fakeListenBrainz{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	queueFilePath := filepath.Join(t.TempDir(), "scrobbles.jsonl")
+	playedAt := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	titles := []string{"first", "second", "refused", "fourth", "fifth"}
+	records := []history.Record{}
+	for i, title := range titles {
+		records = append(records, newRecord(title, playedAt.Add(time.Hour*time.Duration(i))))
+	}
+	checkError(saveQueue(queueFilePath, records), t)
+
+	// listens are kept while the token is refused, and submitted once the service accepts it again
+	fake.setFailWith(http.StatusUnauthorized)
+	scrobbler := NewScrobbler(NewClient(server.URL, "secret"), queueFilePath, 50*time.Millisecond)
+	defer scrobbler.Close()
+	time.Sleep(200 * time.Millisecond)
+	if scrobbler.Pending() != len(titles) || len(fake.getSubmissions()) != 0 {
+		t.Fatalf("expected the listens to be kept while the token is refused, %d are pending", scrobbler.Pending())
+	}
+
+	// only the listen which the service refuses is dropped from a refused batch
+	fake.setRejectTitle("refused")
+	fake.setFailWith(0)
+	waitFor(func() bool { return scrobbler.Pending() == 0 }, t)
+	submitted := []string{}
+	for _, submission := range fake.getSubmissions() {
+		for _, listen := range submission.Payload {
+			submitted = append(submitted, listen.TrackMetadata.TrackName)
+		}
+	}
+	if expected := []string{"first", "second", "fourth", "fifth"}; !reflect.DeepEqual(submitted, expected) {
+		t.Errorf("expected the listens: %q, got: %q", expected, submitted)
+	}
+}
+
+func checkError(err error, t *testing.T) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
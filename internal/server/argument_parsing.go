@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+// checks that commands[0] received between minimum and maximum arguments
+func expectArguments(commands []string, minimum, maximum int) error {
+	argumentCount := len(commands) - 1
+	if argumentCount < minimum || argumentCount > maximum {
+		expected := fmt.Sprint(minimum)
+		if maximum != minimum {
+			expected = fmt.Sprintf("%d-%d", minimum, maximum)
+		}
+		return fmt.Errorf("%s: expected %s args, got %d", commands[0], expected, argumentCount)
+	}
+	return nil
+}
+
+// parses a queue range given as "START:END", "START:" (until the end of the queue) or a single position "POS"
+func parseQueueRange(value string, queueLength int) (start, end int, err error) {
+	startString, endString, isRange := strings.Cut(value, ":")
+	start, err = strconv.Atoi(startString)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid queue position: %s", startString)
+	}
+	if !isRange {
+		return start, start + 1, nil
+	}
+	if endString == "" {
+		return start, queueLength, nil
+	}
+	end, err = strconv.Atoi(endString)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid queue position: %s", endString)
+	}
+	return start, end, nil
+}
+
+// parses a queue position which can also be relative to the current entry:
+// "+N" means N entries after the current one (so "+0" plays next) and "-N" means N entries before it
+func parseQueuePosition(value string, currentPosition int) (int, error) {
+	position, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid queue position: %s", value)
+	}
+	switch {
+	case strings.HasPrefix(value, "+"):
+		return currentPosition + 1 + position, nil
+	case strings.HasPrefix(value, "-"):
+		return currentPosition + position, nil
+	default:
+		return position, nil
+	}
+}
+
+// parses a seek target, which is a time like "80", "1.5" (seconds) or "1m20s", a relative time like "+10s" or "-5",
+// or a percentage of the track like "50%"
+func parseSeekTarget(value string) (playbackmanager.SeekTarget, error) {
+	if percentageString, isPercentage := strings.CutSuffix(value, "%"); isPercentage {
+		percentage, err := strconv.ParseFloat(percentageString, 64)
+		if err != nil {
+			return playbackmanager.SeekTarget{}, fmt.Errorf("invalid seek percentage: %s", value)
+		}
+		return playbackmanager.SeekTarget{Mode: playbackmanager.SeekPercentage, Percentage: percentage}, nil
+	}
+	seekTime, err := parseSeekTime(value)
+	if err != nil {
+		return playbackmanager.SeekTarget{}, err
+	}
+	mode := playbackmanager.SeekAbsolute
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		mode = playbackmanager.SeekRelative
+	}
+	return playbackmanager.SeekTarget{Mode: mode, Time: seekTime}, nil
+}
+
+// parses a time given in (fractional) seconds or as a duration like "1m20s", keeping the sign
+func parseSeekTime(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid seek time: %s", value)
+	}
+	return duration, nil
+}
+
+// parses a duration like "1h30m", a plain number is taken in the given unit
+func parseSleepDuration(value string, unit time.Duration) (time.Duration, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		return time.Duration(number * float64(unit)), nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %s", value)
+	}
+	return duration, nil
+}
+
+func parseOnOff(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "1", "true":
+		return true, nil
+	case "off", "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off, got: %s", value)
+}
+
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+func TestParseSeekTarget(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected playbackmanager.SeekTarget
+	}{
+		{"80", playbackmanager.SeekTarget{Mode: playbackmanager.SeekAbsolute, Time: time.Second * 80}},
+		{"1.25", playbackmanager.SeekTarget{Mode: playbackmanager.SeekAbsolute, Time: time.Millisecond * 1250}},
+		{"1m20s", playbackmanager.SeekTarget{Mode: playbackmanager.SeekAbsolute, Time: time.Second * 80}},
+		{"+10s", playbackmanager.SeekTarget{Mode: playbackmanager.SeekRelative, Time: time.Second * 10}},
+		{"-5", playbackmanager.SeekTarget{Mode: playbackmanager.SeekRelative, Time: -time.Second * 5}},
+		{"50%", playbackmanager.SeekTarget{Mode: playbackmanager.SeekPercentage, Percentage: 50}},
+	}
+	for _, testCase := range testCases {
+		target, err := parseSeekTarget(testCase.value)
+		checkError(err, t)
+		if target != testCase.expected {
+			t.Errorf("%s: expected %+v, got %+v", testCase.value, testCase.expected, target)
+		}
+	}
+	for _, value := range []string{"", "soon", "half%"} {
+		if _, err := parseSeekTarget(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestParseSleepDuration(t *testing.T) {
+	testCases := []struct {
+		value    string
+		unit     time.Duration
+		expected time.Duration
+	}{
+		{"30", time.Minute, time.Minute * 30},
+		{"1.5", time.Minute, time.Second * 90},
+		{"1h30m", time.Minute, time.Minute * 90},
+		{"10", time.Second, time.Second * 10},
+	}
+	for _, testCase := range testCases {
+		duration, err := parseSleepDuration(testCase.value, testCase.unit)
+		checkError(err, t)
+		if duration != testCase.expected {
+			t.Errorf("%s: expected %v, got %v", testCase.value, testCase.expected, duration)
+		}
+	}
+	if _, err := parseSleepDuration("later", time.Minute); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
@@ -2,21 +2,30 @@ package server
 
 import (
 	"fmt"
-	"log"
+	"math"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/arpitpandey992/go-mpd/internal/database"
 	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+	"github.com/arpitpandey992/go-mpd/internal/replaygain"
 )
 
 type AudioRequestsHandler struct {
 	playbackManager *playbackmanager.PlaybackManager
+	database        *database.AudioMeilisearchClient
+	queueAdder      *QueueAdder
+	autoDJ          *AutoDJ // nil without an audio database
 }
 
-func getNewAudioRequestsHandler() *AudioRequestsHandler {
+func getNewAudioRequestsHandler(playbackManager *playbackmanager.PlaybackManager, db *database.AudioMeilisearchClient, queueAdder *QueueAdder, autoDJ *AutoDJ) *AudioRequestsHandler {
 	return &AudioRequestsHandler{
-		playbackManager: playbackmanager.CreatePlaybackManager(),
+		playbackManager: playbackManager,
+		database:        db,
+		queueAdder:      queueAdder,
+		autoDJ:          autoDJ,
 	}
 }
 
@@ -25,36 +34,171 @@ func (arh *AudioRequestsHandler) HandleAudioRequest(commands []string) (string,
 	switch mainCommand {
 	case "add":
 		if len(commands) < 2 {
-			return "", fmt.Errorf("add: filepath missing, expected 1 arg, got 0") // TODO: move this argument parsing logic to a separate centralized module
+			return "", fmt.Errorf("add: filepath missing, expected at least 1 arg, got 0") // TODO: move this argument parsing logic to a separate centralized module
 		}
-		return arh.addToPlaybackQueue(commands[1])
+		return arh.addToPlaybackQueue(commands[1:]), nil
+	case "jobs":
+		if err := expectArguments(commands, 0, 0); err != nil {
+			return "", err
+		}
+		return arh.listAddJobs(), nil
 	case "play":
 		return arh.playCurrentTrackInQueue()
 	case "pause":
 		return arh.pauseCurrentlyPlayingTrack()
 	case "seek":
-		if len(commands) < 2 {
-			return "", fmt.Errorf("seek: duration missing, expected 1 arg, got 0")
+		if err := expectArguments(commands, 1, 2); err != nil {
+			return "", err
 		}
-		return arh.seekCurrentlyPlayingTrack(commands[1])
+		return arh.seekCurrentlyPlayingTrack(commands[1:])
+	case "seekid":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		return arh.seekId(commands[1], commands[2])
 	case "next":
 		return arh.next()
 	case "prev":
 		return arh.previous()
 	case "stop":
 		return arh.stopQueuePlayback()
+	case "status":
+		return arh.status(), nil
+	case "currentsong":
+		return arh.currentSong()
+	case "queue":
+		return arh.listQueue(), nil
+	case "insert":
+		if err := expectArguments(commands, 1, 2); err != nil {
+			return "", err
+		}
+		return arh.insertIntoPlaybackQueue(commands[1:])
+	case "delete":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return arh.deleteFromPlaybackQueue(commands[1])
+	case "move":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		return arh.moveInPlaybackQueue(commands[1], commands[2])
+	case "swap":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		return arh.swapInPlaybackQueue(commands[1], commands[2])
+	case "range":
+		if err := expectArguments(commands, 1, 3); err != nil {
+			return "", err
+		}
+		return arh.setEntryRange(commands[1:])
+	case "priority":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		return arh.setPriority(commands[1], commands[2])
+	case "priorityid":
+		if err := expectArguments(commands, 2, math.MaxInt); err != nil {
+			return "", err
+		}
+		return arh.setPriorityIds(commands[1], commands[2:])
+	case "playnext":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return arh.playNext(commands[1])
+	case "clear":
+		return arh.clearPlaybackQueue()
+	case "shuffle":
+		if err := expectArguments(commands, 0, 1); err != nil {
+			return "", err
+		}
+		return arh.shufflePlaybackQueue(commands[1:])
+	case "undo":
+		if err := expectArguments(commands, 0, 0); err != nil {
+			return "", err
+		}
+		return arh.undo()
+	case "redo":
+		if err := expectArguments(commands, 0, 0); err != nil {
+			return "", err
+		}
+		return arh.redo()
+	case "repeat", "random", "single", "consume":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return arh.setPlaybackOption(mainCommand, commands[1])
+	case "crossfade":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return arh.setCrossfade(commands[1])
+	case "setvol":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return arh.setVolume(commands[1])
+	case "volume":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return arh.changeVolume(commands[1])
+	case "replaygain":
+		if err := expectArguments(commands, 0, 1); err != nil {
+			return "", err
+		}
+		return arh.replayGain(commands[1:])
+	case "mute":
+		arh.playbackManager.SetMuted(true)
+		return "muted", nil
+	case "unmute":
+		arh.playbackManager.SetMuted(false)
+		return "unmuted", nil
+	case "sleep":
+		if err := expectArguments(commands, 0, 2); err != nil {
+			return "", err
+		}
+		return arh.sleep(commands[1:])
+	case "jump":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return arh.jumpToQueuePosition(commands[1])
+	case "loop":
+		if err := expectArguments(commands, 0, 2); err != nil {
+			return "", err
+		}
+		return arh.loop(commands[1:])
+	case "autodj":
+		if err := expectArguments(commands, 0, 1); err != nil {
+			return "", err
+		}
+		return arh.setAutoDJ(commands[1:])
 	default:
 		return "", fmt.Errorf("unknown audio playback command: %s", mainCommand)
 	}
 }
 
-func (arh *AudioRequestsHandler) addToPlaybackQueue(filePath string) (string, error) {
-	err := arh.playbackManager.AddAudioFilesToQueue(filePath)
-	if err != nil {
-		return "", err
+// adds files, directories and globs to the queue, directories and globs are added in the background
+func (arh *AudioRequestsHandler) addToPlaybackQueue(arguments []string) string {
+	job := arh.queueAdder.Add(arguments)
+	if !job.Done {
+		return fmt.Sprintf("adding in the background as job %d, check progress with: audio jobs", job.ID)
 	}
-	log.Printf("added audio file at: %s to playback queue", filePath)
-	return fmt.Sprintf("added %v to playback queue", path.Base(filePath)), nil
+	return describeAddJob(job)
+}
+
+func (arh *AudioRequestsHandler) listAddJobs() string {
+	lines := []string{}
+	for _, job := range arh.queueAdder.Jobs() {
+		lines = append(lines, fmt.Sprintf("%d: %s", job.ID, describeAddJob(job)))
+	}
+	if len(lines) == 0 {
+		return "no add jobs"
+	}
+	return strings.Join(lines, "\n")
 }
 
 func (arh *AudioRequestsHandler) playCurrentTrackInQueue() (string, error) {
@@ -72,16 +216,46 @@ func (arh *AudioRequestsHandler) pauseCurrentlyPlayingTrack() (string, error) {
 	return fmt.Sprintf("Paused: %s", arh.playbackManager.GetCurrentTrackName()), nil
 }
 
-func (arh *AudioRequestsHandler) seekCurrentlyPlayingTrack(durationString string) (string, error) {
-	duration, err := time.ParseDuration(durationString)
+// seeks within the current track, or within the entry at the queue position given before the seek target
+func (arh *AudioRequestsHandler) seekCurrentlyPlayingTrack(args []string) (string, error) {
+	target, err := parseSeekTarget(args[len(args)-1])
 	if err != nil {
 		return "", err
 	}
-	err = arh.playbackManager.Seek(duration)
+	if len(args) == 1 {
+		err = arh.playbackManager.SeekCurrent(target)
+	} else {
+		position, parseErr := parseQueuePosition(args[0], arh.playbackManager.GetStatus().QueuePosition)
+		if parseErr != nil {
+			return "", parseErr
+		}
+		err = arh.playbackManager.SeekQueuePosition(position, target)
+	}
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("Skipped: %s to %v", arh.playbackManager.GetCurrentTrackName(), duration), nil
+	return arh.seekedMessage(), nil
+}
+
+func (arh *AudioRequestsHandler) seekId(idString, targetString string) (string, error) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return "", fmt.Errorf("invalid id: %s", idString)
+	}
+	target, err := parseSeekTarget(targetString)
+	if err != nil {
+		return "", err
+	}
+	err = arh.playbackManager.SeekId(id, target)
+	if err != nil {
+		return "", err
+	}
+	return arh.seekedMessage(), nil
+}
+
+func (arh *AudioRequestsHandler) seekedMessage() string {
+	status := arh.playbackManager.GetStatus()
+	return fmt.Sprintf("Skipped: %s to %v/%v", path.Base(status.CurrentFilePath), status.Elapsed, status.Duration.Truncate(time.Millisecond))
 }
 
 func (arh *AudioRequestsHandler) stopQueuePlayback() (string, error) {
@@ -107,3 +281,482 @@ func (arh *AudioRequestsHandler) previous() (string, error) {
 	}
 	return fmt.Sprintf("Playing: %s", arh.playbackManager.GetCurrentTrackName()), nil
 }
+
+func (arh *AudioRequestsHandler) status() string {
+	status := arh.playbackManager.GetStatus()
+	lines := []string{
+		fmt.Sprintf("state: %s", status.State),
+		fmt.Sprintf("queue: %d/%d", min(status.QueuePosition+1, status.QueueLength), status.QueueLength),
+		fmt.Sprintf("volume: %s, replay gain: %s", formatVolume(status), arh.playbackManager.GetReplayGainSettings().Mode),
+		fmt.Sprintf("options: repeat %s, random %s, single %s, consume %s, crossfade %v",
+			onOff(status.Options.Repeat), onOff(status.Options.Random), onOff(status.Options.Single), onOff(status.Options.Consume), status.Options.Crossfade),
+	}
+	if sleepTimer := arh.playbackManager.GetSleepTimer(); sleepTimer.IsSet() {
+		lines = append(lines, fmt.Sprintf("sleep: %s", formatSleepTimer(sleepTimer)))
+	}
+	if status.State != playbackmanager.PlaybackStateStopped {
+		lines = append(lines,
+			fmt.Sprintf("track: %s", path.Base(status.CurrentFilePath)),
+			fmt.Sprintf("elapsed: %v/%v", status.Elapsed.Truncate(time.Second), status.Duration.Truncate(time.Second)),
+			fmt.Sprintf("format: %d Hz, %d bit, %d channels", status.SampleRate, status.BitDepth, status.Channels),
+		)
+	}
+	if loop, looping := arh.playbackManager.GetLoop(); looping {
+		lines = append(lines, "loop: "+formatLoop(loop))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (arh *AudioRequestsHandler) currentSong() (string, error) {
+	status := arh.playbackManager.GetStatus()
+	if status.State == playbackmanager.PlaybackStateStopped {
+		return "", fmt.Errorf("no track is playing")
+	}
+	metadata, err := findAudioFileMetadata(arh.database, status.CurrentFilePath)
+	if err != nil {
+		return "", err
+	}
+	if metadata == nil {
+		return fmt.Sprintf("%s is not in the database", status.CurrentFilePath), nil
+	}
+	return metadata.ToIndentedJsonString()
+}
+
+func (arh *AudioRequestsHandler) listQueue() string {
+	status := arh.playbackManager.GetStatus()
+	lines := []string{}
+	for position, entry := range arh.playbackManager.GetQueue() {
+		marker := " "
+		if position == status.QueuePosition {
+			marker = "*"
+		}
+		line := fmt.Sprintf("%s%d: [id %d] %s", marker, position, entry.ID, describeQueueEntry(entry))
+		if entry.Priority > 0 {
+			line += fmt.Sprintf(" (priority %d)", entry.Priority)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "playback queue is empty"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// returns the file name of local entries, with the range for virtual tracks, and the whole URL of streams
+func describeQueueEntry(entry playbackmanager.QueueEntry) string {
+	switch entry.Kind {
+	case playbackmanager.SourceKindStream:
+		return fmt.Sprintf("%s (stream)", entry.URI)
+	case playbackmanager.SourceKindVirtual:
+		return fmt.Sprintf("%s (%s)", path.Base(entry.URI), formatTrackRange(*entry.Range))
+	}
+	return path.Base(entry.URI)
+}
+
+func formatTrackRange(trackRange playbackmanager.TrackRange) string {
+	end := "end"
+	if trackRange.End > 0 {
+		end = trackRange.End.String()
+	}
+	return fmt.Sprintf("%v-%s", trackRange.Start, end)
+}
+
+// inserts a file right after the current track, or at the (possibly relative) position given as second argument
+func (arh *AudioRequestsHandler) insertIntoPlaybackQueue(args []string) (string, error) {
+	positionString := "+0"
+	if len(args) > 1 {
+		positionString = args[1]
+	}
+	status := arh.playbackManager.GetStatus()
+	position, err := parseQueuePosition(positionString, status.QueuePosition)
+	if err != nil {
+		return "", err
+	}
+	position = min(position, status.QueueLength)
+	ids, err := arh.playbackManager.InsertAudioFilesToQueue(position, args[0])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("inserted %v at position %d with id %d", path.Base(args[0]), position, ids[0]), nil
+}
+
+func (arh *AudioRequestsHandler) deleteFromPlaybackQueue(rangeString string) (string, error) {
+	start, end, err := parseQueueRange(rangeString, arh.playbackManager.GetStatus().QueueLength)
+	if err != nil {
+		return "", err
+	}
+	err = arh.playbackManager.DeleteFromQueue(start, end)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deleted %d entries from playback queue", end-start), nil
+}
+
+func (arh *AudioRequestsHandler) moveInPlaybackQueue(rangeString string, toString string) (string, error) {
+	status := arh.playbackManager.GetStatus()
+	start, end, err := parseQueueRange(rangeString, status.QueueLength)
+	if err != nil {
+		return "", err
+	}
+	to, err := parseQueuePosition(toString, status.QueuePosition)
+	if err != nil {
+		return "", err
+	}
+	err = arh.playbackManager.MoveInQueue(start, end, to)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("moved %s to position %d", rangeString, to), nil
+}
+
+func (arh *AudioRequestsHandler) swapInPlaybackQueue(firstString string, secondString string) (string, error) {
+	first, err := strconv.Atoi(firstString)
+	if err != nil {
+		return "", fmt.Errorf("invalid queue position: %s", firstString)
+	}
+	second, err := strconv.Atoi(secondString)
+	if err != nil {
+		return "", fmt.Errorf("invalid queue position: %s", secondString)
+	}
+	err = arh.playbackManager.SwapInQueue(first, second)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("swapped positions %d and %d", first, second), nil
+}
+
+// plays only the part between the start and end in args[1:] of the entry with ID args[0], the whole file without them
+func (arh *AudioRequestsHandler) setEntryRange(args []string) (string, error) {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid id: %s", args[0])
+	}
+	if len(args) == 1 {
+		err = arh.playbackManager.SetEntryRange(id, nil)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("id %d plays the whole file", id), nil
+	}
+	trackRange := playbackmanager.TrackRange{}
+	trackRange.Start, err = parseSeekTime(args[1])
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 2 {
+		trackRange.End, err = parseSeekTime(args[2])
+		if err != nil {
+			return "", err
+		}
+	}
+	err = arh.playbackManager.SetEntryRange(id, &trackRange)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("id %d plays %s", id, formatTrackRange(trackRange)), nil
+}
+
+// sets the priority of the queue positions in rangeString, entries with a higher priority are played first
+func (arh *AudioRequestsHandler) setPriority(priorityString, rangeString string) (string, error) {
+	priority, err := strconv.Atoi(priorityString)
+	if err != nil {
+		return "", fmt.Errorf("invalid priority: %s", priorityString)
+	}
+	start, end, err := parseQueueRange(rangeString, arh.playbackManager.GetStatus().QueueLength)
+	if err != nil {
+		return "", err
+	}
+	err = arh.playbackManager.SetPriority(priority, start, end)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("set priority %d for %d entries", priority, end-start), nil
+}
+
+func (arh *AudioRequestsHandler) setPriorityIds(priorityString string, idStrings []string) (string, error) {
+	priority, err := strconv.Atoi(priorityString)
+	if err != nil {
+		return "", fmt.Errorf("invalid priority: %s", priorityString)
+	}
+	ids := []int{}
+	for _, idString := range idStrings {
+		id, err := strconv.Atoi(idString)
+		if err != nil {
+			return "", fmt.Errorf("invalid id: %s", idString)
+		}
+		ids = append(ids, id)
+	}
+	err = arh.playbackManager.SetPriorityIds(priority, ids...)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("set priority %d for %d entries", priority, len(ids)), nil
+}
+
+// plays the entry with the given ID right after the current track, without moving it in the queue
+func (arh *AudioRequestsHandler) playNext(idString string) (string, error) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return "", fmt.Errorf("invalid id: %s", idString)
+	}
+	err = arh.playbackManager.PlayNext(id)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("id %d plays next", id), nil
+}
+
+func (arh *AudioRequestsHandler) clearPlaybackQueue() (string, error) {
+	err := arh.playbackManager.ClearQueue()
+	if err != nil {
+		return "", err
+	}
+	return "Cleared Playback Queue", nil
+}
+
+// shuffles the whole queue, or only the range given as argument
+func (arh *AudioRequestsHandler) shufflePlaybackQueue(args []string) (string, error) {
+	queueLength := arh.playbackManager.GetStatus().QueueLength
+	start, end := 0, queueLength
+	if len(args) > 0 {
+		var err error
+		start, end, err = parseQueueRange(args[0], queueLength)
+		if err != nil {
+			return "", err
+		}
+	}
+	err := arh.playbackManager.ShuffleQueue(start, end)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("shuffled %d entries", end-start), nil
+}
+
+func (arh *AudioRequestsHandler) undo() (string, error) {
+	err := arh.playbackManager.Undo()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("undone, %d entries in the playback queue", arh.playbackManager.GetStatus().QueueLength), nil
+}
+
+func (arh *AudioRequestsHandler) redo() (string, error) {
+	err := arh.playbackManager.Redo()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("redone, %d entries in the playback queue", arh.playbackManager.GetStatus().QueueLength), nil
+}
+
+func (arh *AudioRequestsHandler) jumpToQueuePosition(positionString string) (string, error) {
+	position, err := strconv.Atoi(positionString)
+	if err != nil {
+		return "", fmt.Errorf("invalid queue position: %s", positionString)
+	}
+	err = arh.playbackManager.PlayQueuePosition(position)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Playing: %s", arh.playbackManager.GetCurrentTrackName()), nil
+}
+
+func (arh *AudioRequestsHandler) setPlaybackOption(option string, value string) (string, error) {
+	enabled, err := parseOnOff(value)
+	if err != nil {
+		return "", err
+	}
+	switch option {
+	case "repeat":
+		arh.playbackManager.SetRepeat(enabled)
+	case "random":
+		arh.playbackManager.SetRandom(enabled)
+	case "single":
+		arh.playbackManager.SetSingle(enabled)
+	case "consume":
+		arh.playbackManager.SetConsume(enabled)
+	}
+	return fmt.Sprintf("%s: %s", option, onOff(enabled)), nil
+}
+
+func (arh *AudioRequestsHandler) setCrossfade(secondsString string) (string, error) {
+	seconds, err := strconv.Atoi(secondsString)
+	if err != nil {
+		return "", fmt.Errorf("invalid crossfade duration: %s", secondsString)
+	}
+	err = arh.playbackManager.SetCrossfade(time.Duration(seconds) * time.Second)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("crossfade: %ds", seconds), nil
+}
+
+func (arh *AudioRequestsHandler) setVolume(volumeString string) (string, error) {
+	volume, err := strconv.Atoi(volumeString)
+	if err != nil {
+		return "", fmt.Errorf("invalid volume: %s", volumeString)
+	}
+	err = arh.playbackManager.SetVolume(volume)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("volume: %s", formatVolume(arh.playbackManager.GetStatus())), nil
+}
+
+// changes the volume relatively, by "+N" or "-N"
+func (arh *AudioRequestsHandler) changeVolume(deltaString string) (string, error) {
+	delta, err := strconv.Atoi(deltaString)
+	if err != nil || !strings.ContainsAny(deltaString[:1], "+-") {
+		return "", fmt.Errorf("invalid volume change: %s, expected +N or -N", deltaString)
+	}
+	arh.playbackManager.ChangeVolume(delta)
+	return fmt.Sprintf("volume: %s", formatVolume(arh.playbackManager.GetStatus())), nil
+}
+
+func formatVolume(status playbackmanager.PlaybackStatus) string {
+	if status.Muted {
+		return fmt.Sprintf("%d%% (muted)", status.Volume)
+	}
+	return fmt.Sprintf("%d%%", status.Volume)
+}
+
+// shows the replay gain mode, or changes it when a mode is given
+func (arh *AudioRequestsHandler) replayGain(args []string) (string, error) {
+	if len(args) > 0 {
+		mode, err := replaygain.ParseMode(args[0])
+		if err != nil {
+			return "", err
+		}
+		arh.playbackManager.SetReplayGainMode(mode)
+	}
+	settings := arh.playbackManager.GetReplayGainSettings()
+	return fmt.Sprintf("replay gain: %s, preamp %+.1f dB, clipping prevention %s", settings.Mode, settings.Preamp, onOff(settings.PreventClipping)), nil
+}
+
+// handles "sleep" (shows the timer), "sleep off", "sleep after current|album" and "sleep DURATION [FADEOUT]",
+// where a plain number is in minutes, like "sleep 30 10s"
+func (arh *AudioRequestsHandler) sleep(args []string) (string, error) {
+	if len(args) == 0 {
+		sleepTimer := arh.playbackManager.GetSleepTimer()
+		if !sleepTimer.IsSet() {
+			return "sleep timer: off", nil
+		}
+		return "sleep timer: " + formatSleepTimer(sleepTimer), nil
+	}
+	switch strings.ToLower(args[0]) {
+	case "off", "cancel":
+		arh.playbackManager.CancelSleepTimer()
+		return "sleep timer: off", nil
+	case "after":
+		if len(args) != 2 {
+			return "", fmt.Errorf("sleep after: expected current or album")
+		}
+		switch strings.ToLower(args[1]) {
+		case "current":
+			arh.playbackManager.SetStopAfterCurrent(true)
+			return "stopping after the current track", nil
+		case "album":
+			arh.playbackManager.SetStopAfterAlbum(true)
+			return "stopping after the current album", nil
+		}
+		return "", fmt.Errorf("sleep after: expected current or album, got: %s", args[1])
+	}
+	duration, err := parseSleepDuration(args[0], time.Minute)
+	if err != nil {
+		return "", err
+	}
+	fadeOut := time.Duration(0)
+	if len(args) > 1 {
+		fadeOut, err = parseSleepDuration(args[1], time.Second)
+		if err != nil {
+			return "", err
+		}
+	}
+	err = arh.playbackManager.SetSleepTimer(duration, fadeOut)
+	if err != nil {
+		return "", err
+	}
+	return "sleep timer: " + formatSleepTimer(arh.playbackManager.GetSleepTimer()), nil
+}
+
+// handles "loop" (shows the loop), "loop off", "loop a" and "loop b" which mark the current position as the points
+// of the loop, and "loop A B" with times like the ones of seek
+func (arh *AudioRequestsHandler) loop(args []string) (string, error) {
+	if len(args) == 2 {
+		a, err := parseSeekTime(args[0])
+		if err != nil {
+			return "", err
+		}
+		b, err := parseSeekTime(args[1])
+		if err != nil {
+			return "", err
+		}
+		loop := playbackmanager.LoopRange{A: a, B: b}
+		err = arh.playbackManager.SetLoop(loop)
+		if err != nil {
+			return "", err
+		}
+		return "loop: " + formatLoop(loop), nil
+	}
+	if len(args) == 0 {
+		loop, looping := arh.playbackManager.GetLoop()
+		if !looping {
+			return "loop: off", nil
+		}
+		return "loop: " + formatLoop(loop), nil
+	}
+	switch strings.ToLower(args[0]) {
+	case "off", "clear":
+		arh.playbackManager.ClearLoop()
+		return "loop: off", nil
+	case "a":
+		a, err := arh.playbackManager.MarkLoopStart()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("loop point A: %v", a), nil
+	case "b":
+		loop, err := arh.playbackManager.MarkLoopEnd()
+		if err != nil {
+			return "", err
+		}
+		return "loop: " + formatLoop(loop), nil
+	}
+	return "", fmt.Errorf("loop: expected off, a, b or the times of A and B, got: %s", args[0])
+}
+
+func formatLoop(loop playbackmanager.LoopRange) string {
+	return fmt.Sprintf("%v-%v", loop.A, loop.B)
+}
+
+// shows whether the auto-DJ is on, or turns it on or off when a value is given
+func (arh *AudioRequestsHandler) setAutoDJ(args []string) (string, error) {
+	if arh.autoDJ == nil {
+		return "", fmt.Errorf("auto-DJ needs the audio database")
+	}
+	if len(args) > 0 {
+		enabled, err := parseOnOff(args[0])
+		if err != nil {
+			return "", err
+		}
+		arh.autoDJ.SetEnabled(enabled)
+	}
+	return fmt.Sprintf("auto-DJ: %s", onOff(arh.autoDJ.IsEnabled())), nil
+}
+
+func formatSleepTimer(sleepTimer playbackmanager.SleepTimer) string {
+	parts := []string{}
+	if !sleepTimer.Deadline.IsZero() {
+		part := fmt.Sprintf("pausing in %v", time.Until(sleepTimer.Deadline).Round(time.Second))
+		if sleepTimer.FadeOut > 0 {
+			part += fmt.Sprintf(", fading out over %v", sleepTimer.FadeOut)
+		}
+		parts = append(parts, part)
+	}
+	if sleepTimer.StopAfterCurrent {
+		parts = append(parts, "stopping after the current track")
+	}
+	if sleepTimer.StopAfterAlbum {
+		parts = append(parts, "stopping after the current album")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/autodj"
+	"github.com/arpitpandey992/go-mpd/internal/config"
+	"github.com/arpitpandey992/go-mpd/internal/database"
+	"github.com/arpitpandey992/go-mpd/internal/history"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+const (
+	DEFAULT_AUTO_DJ_MINIMUM_QUEUED = 3
+	DEFAULT_AUTO_DJ_AVOID_RECENT   = 24 * time.Hour
+
+	maximumAutoDJSeeds = 10 // recent plays the chosen tracks are matched against
+)
+
+// AutoDJ keeps the queue of a partition from running dry by appending tracks which fit the recent plays
+type AutoDJ struct {
+	playbackManager *playbackmanager.PlaybackManager
+	selector        *autodj.Selector
+	historyStore    *history.Store // nil when no history is kept, only the plays seen by the auto-DJ are avoided then
+	minimumQueued   int
+	avoidRecent     time.Duration
+	enabled         bool
+	recentPlays     []recentPlay // most recent first
+	lock            sync.Mutex
+	wakeup          chan struct{} // tops up the queue on the goroutine following the playback
+	stopFollowing   func()
+}
+
+type recentPlay struct {
+	playedAt time.Time
+	filePath string
+	metadata *database.AudioFileMetadata // nil when the track is not in the audio database
+}
+
+// creates the auto-DJ of a partition from the config, falling back to the defaults for invalid values
+func newConfiguredAutoDJ(playbackManager *playbackmanager.PlaybackManager, autoDJConfig config.AutoDJConfig, db *database.AudioMeilisearchClient, historyStore *history.Store) *AutoDJ {
+	strategy := autodj.StrategyArtist
+	if autoDJConfig.Strategy != "" {
+		var err error
+		strategy, err = autodj.ParseStrategy(autoDJConfig.Strategy)
+		if err != nil {
+			log.Printf("ignoring auto-DJ strategy from config, error: %v", err)
+			strategy = autodj.StrategyArtist
+		}
+	}
+	selector := autodj.NewSelector(db.SearchAudioFiles, autodj.Settings{
+		Strategy:  strategy,
+		SeedQuery: autoDJConfig.SeedQuery,
+		DateRange: autoDJConfig.DateRange,
+	})
+	minimumQueued := DEFAULT_AUTO_DJ_MINIMUM_QUEUED
+	if autoDJConfig.MinimumQueued > 0 {
+		minimumQueued = autoDJConfig.MinimumQueued
+	}
+	avoidRecent := DEFAULT_AUTO_DJ_AVOID_RECENT
+	if autoDJConfig.AvoidRecentHours > 0 {
+		avoidRecent = time.Duration(autoDJConfig.AvoidRecentHours) * time.Hour
+	}
+	return newAutoDJ(playbackManager, selector, historyStore, minimumQueued, avoidRecent, autoDJConfig.Enabled)
+}
+
+// newAutoDJ starts following the playback of the partition, Close has to be called once it is not needed anymore
+func newAutoDJ(playbackManager *playbackmanager.PlaybackManager, selector *autodj.Selector, historyStore *history.Store, minimumQueued int, avoidRecent time.Duration, enabled bool) *AutoDJ {
+	autoDJ := &AutoDJ{
+		playbackManager: playbackManager,
+		selector:        selector,
+		historyStore:    historyStore,
+		minimumQueued:   minimumQueued,
+		avoidRecent:     avoidRecent,
+		enabled:         enabled,
+		wakeup:          make(chan struct{}, 1),
+	}
+	autoDJ.stopFollowing = autoDJ.followPlayback()
+	return autoDJ
+}
+
+// tops up the queue whenever a track starts, and keeps playing with the appended tracks once the queue ran out during playback
+func (autoDJ *AutoDJ) followPlayback() (stop func()) {
+	subscription := autoDJ.playbackManager.Subscribe(0)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case event, ok := <-subscription.Events:
+				if !ok {
+					return
+				}
+				switch event.Type {
+				case playbackmanager.EventTrackStarted:
+					autoDJ.rememberPlay(event.Entry, event.Time)
+					autoDJ.topUp(false)
+				case playbackmanager.EventQueueEnded:
+					// skipping the last track, or the queue ending while paused, does not start playback
+					autoDJ.topUp(event.Completed)
+				}
+			case <-autoDJ.wakeup:
+				autoDJ.topUp(false)
+			}
+		}
+	}()
+	return func() {
+		subscription.Unsubscribe()
+		<-stopped
+	}
+}
+
+// Close stops following the playback, the auto-DJ does not append anything afterwards
+func (autoDJ *AutoDJ) Close() {
+	autoDJ.stopFollowing()
+}
+
+func (autoDJ *AutoDJ) IsEnabled() bool {
+	autoDJ.lock.Lock()
+	defer autoDJ.lock.Unlock()
+	return autoDJ.enabled
+}
+
+// SetEnabled turns the auto-DJ on or off, the queue is topped up in the background when it is turned on
+func (autoDJ *AutoDJ) SetEnabled(enabled bool) {
+	autoDJ.lock.Lock()
+	autoDJ.enabled = enabled
+	autoDJ.lock.Unlock()
+	if enabled {
+		select {
+		case autoDJ.wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (autoDJ *AutoDJ) rememberPlay(entry playbackmanager.QueueEntry, playedAt time.Time) {
+	autoDJ.lock.Lock()
+	defer autoDJ.lock.Unlock()
+	play := recentPlay{playedAt: playedAt, filePath: getAbsoluteFilePath(entry.URI), metadata: entry.Metadata}
+	autoDJ.recentPlays = append([]recentPlay{play}, autoDJ.recentPlays...)
+	// plays are kept as long as they have to be avoided, but at least the ones used as seeds
+	for len(autoDJ.recentPlays) > maximumAutoDJSeeds && time.Since(autoDJ.recentPlays[len(autoDJ.recentPlays)-1].playedAt) > autoDJ.avoidRecent {
+		autoDJ.recentPlays = autoDJ.recentPlays[:len(autoDJ.recentPlays)-1]
+	}
+}
+
+// appends tracks when fewer than the minimum are left to be played, and starts playing them when resume is set.
+// It is only called by the goroutine following the playback. The lock is held just to read the state of the auto-DJ,
+// not while searching the database, reading the history or adding to the queue
+func (autoDJ *AutoDJ) topUp(resume bool) {
+	autoDJ.lock.Lock()
+	enabled, seeds, recentFilePaths := autoDJ.enabled, autoDJ.getSeeds(), autoDJ.getRecentFilePaths()
+	autoDJ.lock.Unlock()
+	if !enabled {
+		return
+	}
+	remaining := autoDJ.playbackManager.GetUnplayedCount()
+	if remaining >= autoDJ.minimumQueued {
+		return
+	}
+	filePaths, err := autoDJ.selector.Choose(seeds, autoDJ.getExcludedFilePaths(recentFilePaths), autoDJ.minimumQueued-remaining)
+	if err != nil {
+		log.Printf("auto-DJ could not choose tracks, error: %v", err)
+	}
+	if len(filePaths) == 0 {
+		return
+	}
+	err = autoDJ.playbackManager.AddAutomaticAudioFilesToQueue(filePaths...)
+	if err != nil {
+		log.Printf("auto-DJ could not add tracks to the queue, error: %v", err)
+	}
+	if resume {
+		err = autoDJ.playbackManager.Play()
+		if err != nil {
+			log.Printf("auto-DJ could not resume playback, error: %v", err)
+		}
+	}
+}
+
+// the recent plays which are in the audio database, most recent first. Must be called with the lock held
+func (autoDJ *AutoDJ) getSeeds() []database.AudioFileMetadata {
+	seeds := []database.AudioFileMetadata{}
+	for _, play := range autoDJ.recentPlays {
+		if play.metadata != nil && len(seeds) < maximumAutoDJSeeds {
+			seeds = append(seeds, *play.metadata)
+		}
+	}
+	return seeds
+}
+
+// the files of the plays which are too recent to be chosen again. Must be called with the lock held
+func (autoDJ *AutoDJ) getRecentFilePaths() []string {
+	filePaths := []string{}
+	since := time.Now().Add(-autoDJ.avoidRecent)
+	for _, play := range autoDJ.recentPlays {
+		if play.playedAt.After(since) {
+			filePaths = append(filePaths, play.filePath)
+		}
+	}
+	return filePaths
+}
+
+// the tracks in the queue, the recent plays and the ones in the history played too recently to be chosen again
+func (autoDJ *AutoDJ) getExcludedFilePaths(recentFilePaths []string) map[string]bool {
+	excluded := map[string]bool{}
+	for _, entry := range autoDJ.playbackManager.GetQueue() {
+		excluded[getAbsoluteFilePath(entry.URI)] = true
+	}
+	for _, filePath := range recentFilePaths {
+		excluded[filePath] = true
+	}
+	since := time.Now().Add(-autoDJ.avoidRecent)
+	if autoDJ.historyStore != nil {
+		records, err := autoDJ.historyStore.Between(since, time.Now())
+		if err != nil {
+			log.Printf("auto-DJ could not read the listening history, error: %v", err)
+		}
+		for _, record := range records {
+			excluded[getAbsoluteFilePath(record.FilePath)] = true
+		}
+	}
+	return excluded
+}
+
+// file paths in the database are absolute, while the queue and the history keep them the way they were added
+func getAbsoluteFilePath(filePath string) string {
+	absolutePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return filepath.Clean(filePath)
+	}
+	return absolutePath
+}
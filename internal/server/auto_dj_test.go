@@ -0,0 +1,59 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/autodj"
+	"github.com/arpitpandey992/go-mpd/internal/database"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+func TestAutoDJ(t *testing.T) {
+	library := map[string]*database.AudioFileMetadata{}
+	for name, artist := range map[string]string{"sample-3s": "Alpha", "sample-9s": "Alpha", "sample-12s": "Alpha", "sample-15s": "Beta"} {
+		filePath, err := filepath.Abs("../../music/" + name + ".mp3")
+		checkError(err, t)
+		library[filePath] = &database.AudioFileMetadata{FilePath: filePath, Artist: []string{artist}}
+	}
+	search := func(query string, limit int64) ([]database.AudioFileMetadata, error) {
+		results := []database.AudioFileMetadata{}
+		for _, metadata := range library {
+			if strings.Contains(metadata.Artist[0], query) {
+				results = append(results, *metadata)
+			}
+		}
+		return results, nil
+	}
+	playbackManager := playbackmanager.CreatePlaybackManager()
+	playbackManager.SetMetadataResolver(func(filePath string) *database.AudioFileMetadata {
+		return library[getAbsoluteFilePath(filePath)]
+	})
+	selector := autodj.NewSelector(search, autodj.Settings{Strategy: autodj.StrategyArtist, SeedQuery: "Beta"})
+	autoDJ := newAutoDJ(playbackManager, selector, nil, 2, time.Hour, false)
+	defer autoDJ.Close()
+	checkError(playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3"), t)
+
+	// nothing was played yet, so the seed query is searched
+	autoDJ.SetEnabled(true)
+	for i := 0; i < 100 && len(playbackManager.GetQueue()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	queue := playbackManager.GetQueue()
+	if len(queue) != 2 || !strings.HasSuffix(queue[1].URI, "sample-15s.mp3") {
+		t.Fatalf("expected the track found by the seed query to be appended, got: %+v", queue)
+	}
+
+	// playing a track appends one by the same artist, which is not in the queue already
+	checkError(playbackManager.Play(), t)
+	defer playbackManager.Stop()
+	for i := 0; i < 100 && len(playbackManager.GetQueue()) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	queue = playbackManager.GetQueue()
+	if len(queue) != 3 || library[queue[2].URI] == nil || library[queue[2].URI].Artist[0] != "Alpha" || strings.HasSuffix(queue[2].URI, "sample-3s.mp3") {
+		t.Fatalf("expected another track by the artist being played to be appended, got: %+v", queue)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/arpitpandey992/go-mpd/internal/database"
@@ -56,3 +57,15 @@ func (drh *DbRequestsHandler) searchInDb(searchTerm string) (string, error) {
 	}
 	return strings.Join(filePaths, "\n"), nil
 }
+
+// looks up the indexed metadata for a file in the playback queue, returns nil when there is no database or the file is not indexed
+func findAudioFileMetadata(db *database.AudioMeilisearchClient, filePath string) (*database.AudioFileMetadata, error) {
+	if db == nil {
+		return nil, nil
+	}
+	absolutePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return db.FindAudioFileByPath(absolutePath)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/history"
+)
+
+const DEFAULT_HISTORY_COUNT = 10
+
+type HistoryRequestsHandler struct {
+	history *history.Store // nil when the listening history is not configured
+}
+
+func getNewHistoryRequestsHandler(historyStore *history.Store) *HistoryRequestsHandler {
+	return &HistoryRequestsHandler{
+		history: historyStore,
+	}
+}
+
+func (hrh *HistoryRequestsHandler) HandleHistoryRequest(commands []string) (string, error) {
+	if hrh.history == nil {
+		return "", fmt.Errorf("listening history is not configured, set history.file in the config")
+	}
+	mainCommand := strings.ToLower(commands[0])
+	switch mainCommand {
+	case "last":
+		if err := expectArguments(commands, 0, 1); err != nil {
+			return "", err
+		}
+		count := DEFAULT_HISTORY_COUNT
+		if len(commands) > 1 {
+			var err error
+			count, err = strconv.Atoi(commands[1])
+			if err != nil || count < 1 {
+				return "", fmt.Errorf("invalid number of records: %s", commands[1])
+			}
+		}
+		records, err := hrh.history.Last(count)
+		if err != nil {
+			return "", err
+		}
+		return formatHistoryRecords(records), nil
+	case "between":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		from, _, err := parseHistoryTime(commands[1])
+		if err != nil {
+			return "", err
+		}
+		to, isDate, err := parseHistoryTime(commands[2])
+		if err != nil {
+			return "", err
+		}
+		if isDate {
+			// a date includes the whole day
+			to = to.AddDate(0, 0, 1)
+		}
+		records, err := hrh.history.Between(from, to)
+		if err != nil {
+			return "", err
+		}
+		return formatHistoryRecords(records), nil
+	default:
+		return "", fmt.Errorf("unknown history command: %s", mainCommand)
+	}
+}
+
+// parses a local date like "2024-05-01" or a time like "2024-05-01T20:00:00+02:00", and reports whether it was a date
+func parseHistoryTime(value string) (time.Time, bool, error) {
+	date, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err == nil {
+		return date, true, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date: %s, expected YYYY-MM-DD or an RFC 3339 time", value)
+	}
+	return timestamp, false, nil
+}
+
+func formatHistoryRecords(records []history.Record) string {
+	if len(records) == 0 {
+		return "no listens recorded"
+	}
+	lines := []string{}
+	for _, record := range records {
+		name := path.Base(record.FilePath)
+		if len(record.Title) > 0 {
+			name = strings.Join(record.Title, ", ")
+			if len(record.Artist) > 0 {
+				name = strings.Join(record.Artist, ", ") + " - " + name
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (played %v of %v)", record.PlayedAt.Local().Format("2006-01-02 15:04"), name,
+			record.Played.Truncate(time.Second), record.Duration.Truncate(time.Second)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+const (
+	DEFAULT_MAX_LINE_LENGTH = 64 * 1024
+	READ_CHUNK_SIZE         = 4096
+)
+
+var errLineTooLong = errors.New("line too long")
+
+// lineReader splits a byte stream into lines terminated by a (possibly multi-character) delimiter.
+// Partial input is buffered until its delimiter arrives, so a command split across multiple TCP segments
+// is returned as one line and several pipelined commands in one segment are returned one by one, in order.
+type lineReader struct {
+	reader        io.Reader
+	delimiter     []byte
+	maxLineLength int
+
+	buffer     []byte
+	readBuffer []byte
+	discarding bool // set after a line exceeded maxLineLength, until that line's delimiter is seen
+}
+
+func newLineReader(reader io.Reader, delimiter string, maxLineLength int) *lineReader {
+	return &lineReader{
+		reader:        reader,
+		delimiter:     []byte(delimiter),
+		maxLineLength: maxLineLength,
+		buffer:        []byte{},
+		readBuffer:    make([]byte, READ_CHUNK_SIZE),
+	}
+}
+
+// ReadLine returns the next line without its delimiter.
+// When a line exceeds the maximum line length errLineTooLong is returned once, the rest of that line is skipped
+// and reading can continue with the following line. Any other error comes from the underlying reader.
+func (lr *lineReader) ReadLine() (string, error) {
+	for {
+		index := bytes.Index(lr.buffer, lr.delimiter)
+		if index >= 0 {
+			line := string(lr.buffer[:index])
+			lr.buffer = lr.buffer[index+len(lr.delimiter):]
+			if lr.discarding {
+				lr.discarding = false
+				continue
+			}
+			if len(line) > lr.maxLineLength {
+				return "", errLineTooLong
+			}
+			return line, nil
+		}
+
+		if len(lr.buffer) > lr.maxLineLength+len(lr.delimiter) {
+			// keep the tail in case it holds the beginning of a delimiter split across reads
+			lr.buffer = append([]byte{}, lr.buffer[len(lr.buffer)-len(lr.delimiter)+1:]...)
+			if !lr.discarding {
+				lr.discarding = true
+				return "", errLineTooLong
+			}
+		}
+
+		n, err := lr.reader.Read(lr.readBuffer)
+		lr.buffer = append(lr.buffer, lr.readBuffer[:n]...)
+		if err != nil && n == 0 {
+			return "", err
+		}
+	}
+}
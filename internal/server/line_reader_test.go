@@ -0,0 +1,70 @@
+package server
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// chunkedReader returns the given chunks one per Read call, simulating input arriving in separate TCP segments
+type chunkedReader struct {
+	chunks []string
+}
+
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	if len(cr.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, cr.chunks[0])
+	cr.chunks[0] = cr.chunks[0][n:]
+	if cr.chunks[0] == "" {
+		cr.chunks = cr.chunks[1:]
+	}
+	return n, nil
+}
+
+func readAllLines(reader *lineReader) ([]string, []error) {
+	lines, errs := []string{}, []error{}
+	for {
+		line, err := reader.ReadLine()
+		if err == io.EOF {
+			return lines, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestLineReaderSplitAndPipelinedInput(t *testing.T) {
+	reader := newLineReader(&chunkedReader{chunks: []string{"audio pl", "ay\naudio next\naudio ", "prev\n"}}, "\n", 100)
+	lines, errs := readAllLines(reader)
+	expected := []string{"audio play", "audio next", "audio prev"}
+	if len(errs) != 0 || strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected: %q, got: %q (errors: %v)", expected, lines, errs)
+	}
+}
+
+func TestLineReaderMultiCharacterDelimiter(t *testing.T) {
+	reader := newLineReader(&chunkedReader{chunks: []string{"ping\r", "\naudio play\r", "\n"}}, "\r\n", 100)
+	lines, errs := readAllLines(reader)
+	expected := []string{"ping", "audio play"}
+	if len(errs) != 0 || strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected: %q, got: %q (errors: %v)", expected, lines, errs)
+	}
+}
+
+func TestLineReaderLineTooLong(t *testing.T) {
+	longLine := strings.Repeat("a", 50)
+	reader := newLineReader(&chunkedReader{chunks: []string{"ping\n", longLine[:25], longLine[25:] + "\nping\n"}}, "\n", 10)
+	lines, errs := readAllLines(reader)
+	expected := []string{"ping", "ping"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected: %q, got: %q", expected, lines)
+	}
+	if len(errs) != 1 || errs[0] != errLineTooLong {
+		t.Errorf("expected exactly one errLineTooLong, got: %v", errs)
+	}
+}
@@ -2,11 +2,23 @@ package server
 
 import (
 	"fmt"
-	"github.com/arpitpandey992/go-mpd/internal/database"
 	"io"
 	"log"
 	"net"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/audiofiles"
+	"github.com/arpitpandey992/go-mpd/internal/audioplayer"
+	"github.com/arpitpandey992/go-mpd/internal/config"
+	"github.com/arpitpandey992/go-mpd/internal/database"
+	"github.com/arpitpandey992/go-mpd/internal/history"
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+	"github.com/arpitpandey992/go-mpd/internal/playlist"
+	"github.com/arpitpandey992/go-mpd/internal/replaygain"
+	"github.com/arpitpandey992/go-mpd/internal/scrobbler"
 )
 
 // TODO: move these constants to config.yml
@@ -14,35 +26,170 @@ const (
 	DEFAULT_SERVER_PROTOCOL = "tcp"
 	DEFAULT_SERVER_ADDRESS  = "127.0.0.1:6600"
 	DEFAULT_DELIMITER       = "\n"
+	DEFAULT_SERVER_MODE     = SERVER_MODE_GO_MPD
+
+	DEFAULT_DATABASE_POLL_INTERVAL = 10 * time.Second
+	DEFAULT_STATE_SAVE_INTERVAL    = 10 * time.Second
+)
+
+const (
+	SERVER_MODE_GO_MPD = "go-mpd" // custom "audio ..."/"db ..." protocol
+	SERVER_MODE_MPD    = "mpd"    // compatibility mode for existing MPD clients
 )
 
 type Handlers struct {
-	audioRequestHandler *AudioRequestsHandler
-	dbRequestsHandler   *DbRequestsHandler
+	audioRequestHandler      *AudioRequestsHandler
+	dbRequestsHandler        *DbRequestsHandler
+	playlistRequestsHandler  *PlaylistRequestsHandler
+	historyRequestsHandler   *HistoryRequestsHandler
+	partitionRequestsHandler *PartitionRequestsHandler
+	mpdRequestsHandler       *MpdRequestsHandler
 }
 
 type Server struct {
 	Protocol  string
 	Address   string
 	Delimiter string // keeping it as string since we can go from string to byte but not the other way around if we want to support multiple character delimiters
+	Mode      string // one of SERVER_MODE_GO_MPD or SERVER_MODE_MPD
 	listener  net.Listener
+
+	// every connection controls one of the partitions, the player of the default partition is the one
+	// whose state is persisted and whose listens are recorded
+	partitions      *Partitions
+	playbackManager *playbackmanager.PlaybackManager
+
+	stopDatabaseWatcher func()
+
+	playlistStore *playlist.Store // nil when no playlist directory is configured
+
+	queueAdder *QueueAdder // of the default partition, shared so that every connection sees the background additions
+
+	stateFilePath        string // empty when the player state is not persisted
+	stopStatePersistence func()
+
+	historyStore *history.Store       // nil when no history file is configured
+	scrobbler    *scrobbler.Scrobbler // nil when no scrobbler token is configured
 }
 
-func CreateAndStartServer(db *database.AudioMeilisearchClient) *Server {
+func CreateAndStartServer(config *config.Config, db *database.AudioMeilisearchClient) *Server {
 	// TODO: make sure to have a close function which will release all resources. Keep a handler ready for managing go routines
+	mode := config.Server.Mode
+	if mode == "" {
+		mode = DEFAULT_SERVER_MODE
+	}
+	if mode != SERVER_MODE_GO_MPD && mode != SERVER_MODE_MPD {
+		log.Fatalf("unknown server mode: %s", mode)
+	}
 	listener := getListener(DEFAULT_SERVER_PROTOCOL, DEFAULT_SERVER_ADDRESS)
 	server := &Server{
 		Address:   DEFAULT_SERVER_ADDRESS,
 		Protocol:  DEFAULT_SERVER_PROTOCOL,
 		Delimiter: DEFAULT_DELIMITER,
+		Mode:      mode,
 		listener:  listener,
+
+		stateFilePath: config.State.File,
+	}
+	if config.History.File != "" {
+		server.historyStore = history.NewStore(config.History.File)
+	}
+	if config.Scrobbler.Token != "" {
+		client := scrobbler.NewClient(config.Scrobbler.Endpoint, config.Scrobbler.Token)
+		server.scrobbler = scrobbler.NewScrobbler(client, config.Scrobbler.QueueFile, scrobbler.DefaultRetryInterval)
+	}
+	// the database, the finder, the history and the scrobbler are shared by every partition
+	finder := newAudioFileFinder(config.Audio.ScanFormats, db)
+	newPartition := func(name string) *Partition {
+		playbackManager := playbackmanager.CreatePlaybackManager()
+		configurePlaybackManager(playbackManager, config.Player, db)
+		partition := &Partition{Name: name, playbackManager: playbackManager, queueAdder: newQueueAdder(playbackManager, finder)}
+		if db != nil {
+			partition.autoDJ = newConfiguredAutoDJ(playbackManager, config.AutoDJ, db, server.historyStore)
+		}
+		if server.historyStore != nil || server.scrobbler != nil {
+			partition.stopListens = server.followListens(playbackManager)
+		}
+		return partition
+	}
+	defaultPartition := newPartition(DEFAULT_PARTITION_NAME)
+	server.playbackManager, server.queueAdder = defaultPartition.playbackManager, defaultPartition.queueAdder
+	server.partitions = newPartitions(defaultPartition, newPartition)
+	if config.Audio.PlaylistDirectory != "" {
+		server.playlistStore = playlist.NewStore(config.Audio.PlaylistDirectory, config.Audio.ScanDirectories)
+	}
+	if server.stateFilePath != "" {
+		server.restorePlayerState(config.State.ResumePlayback)
+		server.stopStatePersistence = server.persistPlayerState(DEFAULT_STATE_SAVE_INTERVAL)
+	}
+	if db != nil {
+		server.stopDatabaseWatcher = db.WatchForUpdates(DEFAULT_DATABASE_POLL_INTERVAL, func() {
+			server.partitions.NotifyAll(idle.SubsystemDatabase)
+		})
 	}
 	go server.handleIncomingConnections(db)
 	return server
 }
 
+func configurePlaybackManager(playbackManager *playbackmanager.PlaybackManager, playerConfig config.PlayerConfig, db *database.AudioMeilisearchClient) {
+	err := playbackManager.SetCrossfade(time.Duration(playerConfig.Crossfade) * time.Second)
+	if err != nil {
+		log.Printf("ignoring crossfade from config, error: %v", err)
+	}
+	playbackManager.SetSkipCrossfadeWithinAlbum(playerConfig.SkipCrossfadeWithinAlbum)
+	replayGainMode := replaygain.ModeOff
+	if playerConfig.ReplayGain.Mode != "" {
+		replayGainMode, err = replaygain.ParseMode(playerConfig.ReplayGain.Mode)
+		if err != nil {
+			log.Printf("ignoring replay gain mode from config, error: %v", err)
+			replayGainMode = replaygain.ModeOff
+		}
+	}
+	playbackManager.SetReplayGainSettings(replaygain.Settings{
+		Mode:            replayGainMode,
+		Preamp:          playerConfig.ReplayGain.Preamp,
+		PreventClipping: !playerConfig.ReplayGain.AllowClipping,
+	})
+	if db != nil {
+		playbackManager.SetMetadataResolver(newMetadataResolver(db))
+	}
+}
+
+// looks up files in the audio database, returning nil for files which are not in it
+func newMetadataResolver(db *database.AudioMeilisearchClient) func(filePath string) *database.AudioFileMetadata {
+	return func(filePath string) *database.AudioFileMetadata {
+		metadata, err := findAudioFileMetadata(db, filePath)
+		if err != nil {
+			log.Printf("could not look up metadata for %s, error: %v", filePath, err)
+		}
+		return metadata
+	}
+}
+
+// creates the finder used to expand additions to the queue, files in a directory are ordered by their disc and track numbers from the database
+func newAudioFileFinder(scanFormats []string, db *database.AudioMeilisearchClient) *audiofiles.Finder {
+	if len(scanFormats) == 0 {
+		scanFormats = audioplayer.SupportedExtensions
+	}
+	finder := audiofiles.NewFinder(scanFormats)
+	if db != nil {
+		// the metadata found is kept with the files, so that it is not looked up again when they are added to the queue
+		finder.SetMetadataResolver(newMetadataResolver(db))
+	}
+	return finder
+}
+
 func (server *Server) Close() {
 	server.listener.Close()
+	if server.stopDatabaseWatcher != nil {
+		server.stopDatabaseWatcher()
+	}
+	if server.stopStatePersistence != nil {
+		server.stopStatePersistence()
+	}
+	server.partitions.Close()
+	if server.scrobbler != nil {
+		server.scrobbler.Close()
+	}
 }
 
 func (server *Server) handleIncomingConnections(db *database.AudioMeilisearchClient) {
@@ -57,7 +204,17 @@ func (server *Server) handleIncomingConnections(db *database.AudioMeilisearchCli
 			continue
 		}
 		log.Print("successfully connected with incoming client")
-		handlers := &Handlers{audioRequestHandler: getNewAudioRequestsHandler(), dbRequestsHandler: getNewDbRequestsHandler(db)}
+		handlers := &Handlers{}
+		if server.Mode == SERVER_MODE_MPD {
+			handlers.mpdRequestsHandler = getNewMpdRequestsHandler(server.partitions, db, server.playlistStore, &lockedWriter{writer: conn})
+		} else {
+			handlers.partitionRequestsHandler = getNewPartitionRequestsHandler(server.partitions, func(partition *Partition) {
+				handlers.audioRequestHandler = getNewAudioRequestsHandler(partition.playbackManager, db, partition.queueAdder, partition.autoDJ)
+				handlers.playlistRequestsHandler = getNewPlaylistRequestsHandler(partition.playbackManager, server.playlistStore)
+			})
+			handlers.dbRequestsHandler = getNewDbRequestsHandler(db)
+			handlers.historyRequestsHandler = getNewHistoryRequestsHandler(server.historyStore)
+		}
 		server.sendWelcomeMessageToConnectionClient(conn)
 		go server.handleConnection(conn, handlers)
 	}
@@ -74,17 +231,38 @@ func getListener(protocol string, server_address string) net.Listener {
 
 func (server *Server) handleConnection(conn net.Conn, handlers *Handlers) {
 	defer conn.Close()
-	buf := make([]byte, 2500)
+	if handlers.mpdRequestsHandler != nil {
+		defer handlers.mpdRequestsHandler.Close()
+	}
+	if handlers.partitionRequestsHandler != nil {
+		defer handlers.partitionRequestsHandler.Close()
+	}
+	reader := newLineReader(conn, server.Delimiter, DEFAULT_MAX_LINE_LENGTH)
 	for {
-		n, err := conn.Read(buf)
+		line, err := reader.ReadLine()
+		if err == errLineTooLong {
+			log.Printf("error: discarding request from %s longer than %d bytes", conn.RemoteAddr(), DEFAULT_MAX_LINE_LENGTH)
+			if server.Mode == SERVER_MODE_MPD {
+				_, _ = handlers.mpdRequestsHandler.output.Write([]byte(formatMpdAck(newMpdAckError(ACK_ERROR_ARG, "line too long"), "", 0)))
+			} else {
+				_ = server.sendMessageToConnectionClient(fmt.Sprintf("error: request exceeds the maximum length of %d bytes", DEFAULT_MAX_LINE_LENGTH), conn)
+			}
+			continue
+		}
 		if err != nil {
 			if err != io.EOF {
 				log.Print(err)
 			}
 			return
 		}
-		log.Printf("server received: %q", buf[:n])
-		err = server.handleIncomingRequest(string(buf[:n]), conn, handlers)
+		log.Printf("server received: %q", line)
+		if server.Mode == SERVER_MODE_MPD {
+			if !server.handleIncomingMpdRequest(line, conn, handlers.mpdRequestsHandler) {
+				return
+			}
+			continue
+		}
+		err = server.handleIncomingRequest(line, conn, handlers)
 		if err != nil {
 			log.Print("error: ", err)
 			_ = server.sendMessageToConnectionClient("error: "+err.Error(), conn)
@@ -93,61 +271,124 @@ func (server *Server) handleConnection(conn net.Conn, handlers *Handlers) {
 }
 
 func (server *Server) handleIncomingRequest(command string, conn net.Conn, handlers *Handlers) error {
-	commands := server.breakIncomingCommandToMultipleCommands(command)
-	for _, splitCommand := range commands {
-		chunks := server.breakCommandIntoChunks(splitCommand)
-		log.Printf("debug: commands: %s", strings.Join(chunks, ", "))
-		for i, chunk := range chunks {
-			chunks[i] = strings.TrimSpace(chunk)
-		}
-		if len(chunks) == 0 {
-			return nil
-		}
-		requestType := chunks[0]
-		switch requestType {
-		case "ping":
-			err := server.sendMessageToConnectionClient("pong", conn)
-			if err != nil {
-				return fmt.Errorf("error while responding to incoming ping. error: %s", err.Error())
-			}
-		case "audio":
-			if len(chunks) < 2 {
-				return fmt.Errorf("audio command expects at least one argument")
-			}
-			returnMessage, err := handlers.audioRequestHandler.HandleAudioRequest(chunks[1:])
-			if err != nil {
-				return err
-			}
-			if returnMessage != "" {
-				_ = server.sendMessageToConnectionClient(returnMessage, conn)
-			}
-		case "db":
-			if len(chunks) < 2 {
-				return fmt.Errorf("database command expects at least one argument")
-			}
-			returnMessage, err := handlers.dbRequestsHandler.HandleDbRequest(chunks[1:])
-			if err != nil {
-				return err
-			}
-			if returnMessage != "" {
-				_ = server.sendMessageToConnectionClient(returnMessage, conn)
-			}
-
-		default:
-			return fmt.Errorf("invalid request type: %s", requestType)
+	chunks := server.breakCommandIntoChunks(command)
+	log.Printf("debug: commands: %s", strings.Join(chunks, ", "))
+	for i, chunk := range chunks {
+		chunks[i] = strings.TrimSpace(chunk)
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	requestType := chunks[0]
+	switch requestType {
+	case "ping":
+		err := server.sendMessageToConnectionClient("pong", conn)
+		if err != nil {
+			return fmt.Errorf("error while responding to incoming ping. error: %s", err.Error())
+		}
+	case "audio":
+		if len(chunks) < 2 {
+			return fmt.Errorf("audio command expects at least one argument")
+		}
+		returnMessage, err := handlers.audioRequestHandler.HandleAudioRequest(chunks[1:])
+		if err != nil {
+			return err
+		}
+		if returnMessage != "" {
+			_ = server.sendMessageToConnectionClient(returnMessage, conn)
+		}
+	case "db":
+		if len(chunks) < 2 {
+			return fmt.Errorf("database command expects at least one argument")
+		}
+		returnMessage, err := handlers.dbRequestsHandler.HandleDbRequest(chunks[1:])
+		if err != nil {
+			return err
+		}
+		if returnMessage != "" {
+			_ = server.sendMessageToConnectionClient(returnMessage, conn)
+		}
+	case "playlist":
+		if len(chunks) < 2 {
+			return fmt.Errorf("playlist command expects at least one argument")
+		}
+		returnMessage, err := handlers.playlistRequestsHandler.HandlePlaylistRequest(chunks[1:])
+		if err != nil {
+			return err
+		}
+		if returnMessage != "" {
+			_ = server.sendMessageToConnectionClient(returnMessage, conn)
+		}
+	case "history":
+		if len(chunks) < 2 {
+			return fmt.Errorf("history command expects at least one argument")
+		}
+		returnMessage, err := handlers.historyRequestsHandler.HandleHistoryRequest(chunks[1:])
+		if err != nil {
+			return err
+		}
+		if returnMessage != "" {
+			_ = server.sendMessageToConnectionClient(returnMessage, conn)
+		}
+	case "partition":
+		if len(chunks) < 2 {
+			return fmt.Errorf("partition command expects at least one argument")
 		}
+		returnMessage, err := handlers.partitionRequestsHandler.HandlePartitionRequest(chunks[1:])
+		if err != nil {
+			return err
+		}
+		if returnMessage != "" {
+			_ = server.sendMessageToConnectionClient(returnMessage, conn)
+		}
+
+	default:
+		return fmt.Errorf("invalid request type: %s", requestType)
 	}
 	return nil
 }
 
+// handles an incoming MPD command line, returns false when the connection should be closed
+func (server *Server) handleIncomingMpdRequest(line string, conn net.Conn, handler *MpdRequestsHandler) bool {
+	response := handler.HandleMpdLine(strings.TrimRight(line, "\r"))
+	if handler.closeRequested {
+		return false
+	}
+	if response == "" {
+		return true
+	}
+	_, err := handler.output.Write([]byte(response))
+	if err != nil {
+		log.Printf("error: could not respond to %s, error: %s", conn.RemoteAddr(), err)
+		return false
+	}
+	return true
+}
+
 func (server *Server) sendWelcomeMessageToConnectionClient(conn net.Conn) {
 	welcomeMessage := "Welcome to Go-MPD!"
+	if server.Mode == SERVER_MODE_MPD {
+		welcomeMessage = "OK MPD " + MPD_PROTOCOL_VERSION
+	}
 	err := server.sendMessageToConnectionClient(welcomeMessage, conn)
 	if err != nil {
 		log.Printf("error: could not send welcome message to %s, error: %s", conn.RemoteAddr(), err)
 	}
 }
 
+// lockedWriter serializes the writes to a connection, MPD responses are written by the connection goroutine
+// and by the one waiting for an idle to end, and must not interleave
+type lockedWriter struct {
+	writer io.Writer
+	lock   sync.Mutex
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	return lw.writer.Write(p)
+}
+
 func (server *Server) sendMessageToConnectionClient(message string, conn net.Conn) error {
 	_, err := conn.Write([]byte(message + DEFAULT_DELIMITER))
 	return err
@@ -176,11 +417,6 @@ func (server *Server) breakCommandIntoChunks(command string) []string {
 	return chunks
 }
 
-func (server *Server) breakIncomingCommandToMultipleCommands(command string) []string {
-	command = strings.TrimSpace(command)
-	return strings.Split(command, server.Delimiter)
-}
-
 func isListenerClosedError(err error) bool {
 	if opErr, ok := err.(*net.OpError); ok {
 		if opErr.Err.Error() == "use of closed network connection" {
@@ -44,7 +44,7 @@ func setup() error {
 		return err
 	}
 	db := database.GetNewAudioMeiliSearchClient(config)
-	server = CreateAndStartServer(db)
+	server = CreateAndStartServer(config, db)
 	println("connecting to server")
 	conn, err = net.Dial("tcp", server.Address)
 	if err != nil {
@@ -65,10 +65,7 @@ func teardown() {
 }
 
 func TestServerPing(t *testing.T) {
-	message := "ping"
-	var err error
-	_, err = conn.Write([]byte(message))
-	checkError(err, t)
+	sendMessageToServer("ping", t)
 	response := extractLineFromConnection(t)
 	expectedResult := "pong" + server.Delimiter
 	if response != expectedResult {
@@ -112,7 +109,7 @@ func TestServerSongPlayback(t *testing.T) {
 }
 
 func sendMessageToServer(message string, t *testing.T) {
-	_, err := conn.Write([]byte(message))
+	_, err := conn.Write([]byte(message + server.Delimiter))
 	checkError(err, t)
 }
 
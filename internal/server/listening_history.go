@@ -0,0 +1,150 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/history"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+// listenTracker adds up how much of the current track is actually heard. It follows the position within the track,
+// so pauses and the parts skipped by seeking are left out, and parts heard again after seeking back count only once.
+// Nothing is counted while the track loops, as the loop jumps back without any event, nor while the player has no
+// enabled output
+type listenTracker struct {
+	entry     *playbackmanager.QueueEntry // nil when no track was started
+	startedAt time.Time
+	playing   bool
+	looping   bool
+	audible   bool
+	position  time.Duration // where the part played since the last event started
+	heard     []heardPart   // sorted and without overlaps
+}
+
+// heardPart is a part of the track from start until end
+type heardPart struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// follows the playback events and returns a record once a track which was listened to long enough is finished
+func (tracker *listenTracker) handle(event playbackmanager.Event) *history.Record {
+	switch event.Type {
+	case playbackmanager.EventTrackStarted:
+		entry := event.Entry
+		tracker.entry, tracker.startedAt, tracker.heard = &entry, event.Time, nil
+		tracker.playing, tracker.looping, tracker.audible, tracker.position = true, false, event.Audible, event.Elapsed
+	case playbackmanager.EventPaused:
+		tracker.hear(event.Elapsed)
+		tracker.playing = false
+	case playbackmanager.EventResumed:
+		tracker.playing, tracker.position = true, event.Elapsed
+	case playbackmanager.EventSeeked:
+		tracker.hear(event.From)
+		tracker.position = event.Elapsed
+	case playbackmanager.EventLoopSet:
+		tracker.hear(event.Elapsed)
+		tracker.looping = true
+	case playbackmanager.EventLoopCleared:
+		tracker.looping, tracker.position = false, event.Elapsed
+	case playbackmanager.EventAudibility:
+		tracker.hear(event.Elapsed)
+		tracker.audible = event.Audible
+	case playbackmanager.EventTrackFinished:
+		if tracker.entry == nil || tracker.entry.ID != event.Entry.ID {
+			return nil
+		}
+		tracker.hear(event.Elapsed)
+		entry, played := *tracker.entry, tracker.getPlayed()
+		tracker.entry = nil
+		if !history.IsListen(event.Duration, played) {
+			return nil
+		}
+		return newHistoryRecord(entry, tracker.startedAt, event.Duration, played)
+	}
+	return nil
+}
+
+// adds the part from the last position until the given one to the heard parts, if it was played
+func (tracker *listenTracker) hear(position time.Duration) {
+	if tracker.entry != nil && tracker.playing && !tracker.looping && tracker.audible && position > tracker.position {
+		tracker.heard = mergeHeardPart(tracker.heard, heardPart{start: tracker.position, end: position})
+	}
+	tracker.position = position
+}
+
+func (tracker *listenTracker) getPlayed() time.Duration {
+	played := time.Duration(0)
+	for _, part := range tracker.heard {
+		played += part.end - part.start
+	}
+	return played
+}
+
+// inserts the part into the sorted parts, joining it with the ones it overlaps or touches
+func mergeHeardPart(parts []heardPart, part heardPart) []heardPart {
+	merged := []heardPart{}
+	for _, existing := range parts {
+		switch {
+		case existing.end < part.start:
+			merged = append(merged, existing)
+		case part.end < existing.start:
+			merged = append(merged, part)
+			part = existing
+		default:
+			part = heardPart{start: min(part.start, existing.start), end: max(part.end, existing.end)}
+		}
+	}
+	return append(merged, part)
+}
+
+func newHistoryRecord(entry playbackmanager.QueueEntry, playedAt time.Time, duration, played time.Duration) *history.Record {
+	record := &history.Record{
+		PlayedAt: playedAt,
+		FilePath: entry.URI,
+		Duration: duration,
+		Played:   played,
+	}
+	if entry.Metadata != nil {
+		record.Title = entry.Metadata.Title
+		record.Artist = entry.Metadata.Artist
+		record.Album = entry.Metadata.Album
+		record.AlbumArtist = entry.Metadata.AlbumArtist
+		record.TrackNumber = entry.Metadata.TrackNumber
+	}
+	return record
+}
+
+// appends every listen of the player to the history and scrobbles it, until the returned function is called
+func (server *Server) followListens(playbackManager *playbackmanager.PlaybackManager) (stop func()) {
+	subscription := playbackManager.Subscribe(0)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		tracker := &listenTracker{}
+		for event := range subscription.Events {
+			// a track played by a partition without the output is not heard by anyone
+			if event.Type == playbackmanager.EventTrackStarted && event.Audible && server.scrobbler != nil {
+				server.scrobbler.NowPlaying(*newHistoryRecord(event.Entry, event.Time, event.Duration, 0))
+			}
+			record := tracker.handle(event)
+			if record == nil {
+				continue
+			}
+			if server.historyStore != nil {
+				err := server.historyStore.Append(*record)
+				if err != nil {
+					log.Printf("could not record listen of %s, error: %v", record.FilePath, err)
+				}
+			}
+			if server.scrobbler != nil {
+				server.scrobbler.Scrobble(*record)
+			}
+		}
+	}()
+	return func() {
+		subscription.Unsubscribe()
+		<-stopped
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+func TestListenTracker(t *testing.T) {
+	start := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	entry := playbackmanager.QueueEntry{ID: 1, URI: "music/track.mp3", Metadata: &database.AudioFileMetadata{Title: []string{"Track"}}}
+	// the event happens seconds after the start, at elapsed seconds within the track
+	event := func(eventType playbackmanager.EventType, seconds, elapsed int) playbackmanager.Event {
+		return playbackmanager.Event{Type: eventType, Time: start.Add(time.Duration(seconds) * time.Second), Entry: entry,
+			Elapsed: time.Duration(elapsed) * time.Second, Duration: time.Minute * 3, Audible: true}
+	}
+	seeked := func(seconds, from, to int) playbackmanager.Event {
+		seekedEvent := event(playbackmanager.EventSeeked, seconds, to)
+		seekedEvent.From = time.Duration(from) * time.Second
+		return seekedEvent
+	}
+
+	tracker := &listenTracker{}
+	for _, e := range []playbackmanager.Event{
+		event(playbackmanager.EventTrackStarted, 0, 0),
+		event(playbackmanager.EventPaused, 60, 60),
+		event(playbackmanager.EventResumed, 600, 60),
+		seeked(620, 80, 150),
+	} {
+		if tracker.handle(e) != nil {
+			t.Fatalf("unexpected record for: %s", e.Type)
+		}
+	}
+	record := tracker.handle(event(playbackmanager.EventTrackFinished, 630, 160))
+	if record == nil || record.Played != 90*time.Second || !record.PlayedAt.Equal(start) || record.Title[0] != "Track" {
+		t.Errorf("expected a listen of 90s without the pause and the skipped part, got: %+v", record)
+	}
+
+	tracker.handle(event(playbackmanager.EventTrackStarted, 700, 0))
+	if record := tracker.handle(event(playbackmanager.EventTrackFinished, 760, 60)); record != nil {
+		t.Errorf("a track skipped after a minute should not be recorded, got: %+v", record)
+	}
+	if record := tracker.handle(event(playbackmanager.EventTrackFinished, 900, 60)); record != nil {
+		t.Errorf("a track which was never started should not be recorded, got: %+v", record)
+	}
+
+	// the same part heard again after seeking back counts once
+	tracker.handle(event(playbackmanager.EventTrackStarted, 1000, 0))
+	tracker.handle(seeked(1060, 60, 0))
+	tracker.handle(seeked(1120, 60, 0))
+	if record := tracker.handle(event(playbackmanager.EventTrackFinished, 1170, 50)); record != nil {
+		t.Errorf("a minute heard three times should not be recorded, got: %+v", record)
+	}
+
+	// a partition without the output plays to nobody
+	tracker.handle(event(playbackmanager.EventTrackStarted, 1200, 0))
+	silenced := event(playbackmanager.EventAudibility, 1230, 30)
+	silenced.Audible = false
+	tracker.handle(silenced)
+	tracker.handle(event(playbackmanager.EventAudibility, 1370, 170))
+	if record := tracker.handle(event(playbackmanager.EventTrackFinished, 1380, 180)); record != nil {
+		t.Errorf("a track played without the output should not be recorded, got: %+v", record)
+	}
+}
+
+func TestListenTrackerLoop(t *testing.T) {
+	start := time.Now()
+	entry := playbackmanager.QueueEntry{ID: 1, URI: "music/track.mp3"}
+	event := func(eventType playbackmanager.EventType, seconds, elapsed int) playbackmanager.Event {
+		return playbackmanager.Event{Type: eventType, Time: start.Add(time.Duration(seconds) * time.Second), Entry: entry,
+			Elapsed: time.Duration(elapsed) * time.Second, Duration: time.Minute * 3, Audible: true}
+	}
+
+	// a loop from 20s to 40s replayed for ten minutes, then cleared and skipped soon after
+	tracker := &listenTracker{}
+	tracker.handle(event(playbackmanager.EventTrackStarted, 0, 0))
+	tracker.handle(event(playbackmanager.EventLoopSet, 30, 30))
+	tracker.handle(event(playbackmanager.EventPaused, 300, 25))
+	tracker.handle(event(playbackmanager.EventResumed, 310, 25))
+	tracker.handle(event(playbackmanager.EventLoopCleared, 630, 35))
+	if record := tracker.handle(event(playbackmanager.EventTrackFinished, 645, 50)); record != nil {
+		t.Errorf("a looped part should not make the track a listen, got: %+v", record)
+	}
+
+	tracker.handle(event(playbackmanager.EventTrackStarted, 700, 0))
+	tracker.handle(event(playbackmanager.EventLoopSet, 730, 30))
+	tracker.handle(event(playbackmanager.EventLoopCleared, 1000, 35))
+	record := tracker.handle(event(playbackmanager.EventTrackFinished, 1145, 180))
+	if record == nil || record.Played != 175*time.Second {
+		t.Errorf("expected the track heard apart from the loop to be recorded, got: %+v", record)
+	}
+}
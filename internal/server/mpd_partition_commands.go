@@ -0,0 +1,74 @@
+package server
+
+// handles the MPD commands for partitions and the outputs assigned to them
+func (mrh *MpdRequestsHandler) handlePartitionCommand(command string, args []string) (string, error) {
+	switch command {
+	case "partition":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		partition, err := mrh.partitions.acquire(args[0])
+		if err != nil {
+			return "", newMpdAckError(ACK_ERROR_NO_EXIST, "%s", err.Error())
+		}
+		mrh.usePartition(partition)
+		return "", nil
+	case "listpartitions":
+		if err := checkMpdArgumentCount(command, args, 0, 0); err != nil {
+			return "", err
+		}
+		response := &mpdResponseBuilder{}
+		for _, name := range mrh.partitions.Names() {
+			response.add("partition", name)
+		}
+		return response.String(), nil
+	case "newpartition":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		return "", wrapPartitionError(mrh.partitions.Create(args[0]), ACK_ERROR_EXIST)
+	case "delpartition":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		return "", wrapPartitionError(mrh.partitions.Delete(args[0]), ACK_ERROR_ARG)
+	case "moveoutput":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		return "", wrapPartitionError(mrh.partitions.MoveOutput(args[0], mrh.partition.Name), ACK_ERROR_NO_EXIST)
+	case "outputs":
+		if err := checkMpdArgumentCount(command, args, 0, 0); err != nil {
+			return "", err
+		}
+		response := &mpdResponseBuilder{}
+		for _, output := range mrh.partitions.Outputs(mrh.partition.Name) {
+			response.add("outputid", output.ID)
+			response.add("outputname", output.Name)
+			response.add("plugin", "beep")
+			response.add("outputenabled", mpdBoolean(output.Enabled))
+		}
+		return response.String(), nil
+	default:
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		id, err := parseMpdInteger(args[0])
+		if err != nil {
+			return "", err
+		}
+		var enabled *bool // toggleoutput
+		if command != "toggleoutput" {
+			enable := command == "enableoutput"
+			enabled = &enable
+		}
+		return "", wrapPartitionError(mrh.partitions.SetOutputEnabled(mrh.partition.Name, id, enabled), ACK_ERROR_NO_EXIST)
+	}
+}
+
+func wrapPartitionError(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	return newMpdAckError(code, "%s", err.Error())
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/playlist"
+)
+
+// handles the MPD commands which work on stored playlists
+func (mrh *MpdRequestsHandler) handleStoredPlaylistCommand(command string, args []string) (string, error) {
+	if mrh.playlists == nil {
+		return "", newMpdAckError(ACK_ERROR_SYSTEM, "stored playlists are not configured")
+	}
+	switch command {
+	case "save":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		return "", mrh.storedPlaylistModified(mrh.playlists.Save(args[0], mrh.playbackManager.GetQueue()))
+	case "load":
+		if err := checkMpdArgumentCount(command, args, 1, 2); err != nil {
+			return "", err
+		}
+		return "", mrh.loadStoredPlaylist(args)
+	case "listplaylists":
+		if err := checkMpdArgumentCount(command, args, 0, 0); err != nil {
+			return "", err
+		}
+		playlists, err := mrh.playlists.List()
+		if err != nil {
+			return "", wrapStoredPlaylistError(err)
+		}
+		response := &mpdResponseBuilder{}
+		for _, info := range playlists {
+			response.add("playlist", info.Name)
+			response.add("Last-Modified", info.LastModified.UTC().Format(time.RFC3339))
+		}
+		return response.String(), nil
+	case "listplaylist", "listplaylistinfo":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		entries, err := mrh.playlists.Load(args[0])
+		if err != nil {
+			return "", wrapStoredPlaylistError(err)
+		}
+		response := &mpdResponseBuilder{}
+		for _, entry := range entries {
+			if command == "listplaylistinfo" {
+				mrh.addMpdSongFile(response, entry.URI)
+			} else {
+				response.add("file", entry.URI)
+			}
+			if entry.Range != nil {
+				response.add("Range", formatMpdRange(*entry.Range))
+			}
+		}
+		return response.String(), nil
+	case "rename":
+		if err := checkMpdArgumentCount(command, args, 2, 2); err != nil {
+			return "", err
+		}
+		return "", mrh.storedPlaylistModified(mrh.playlists.Rename(args[0], args[1]))
+	case "rm":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		return "", mrh.storedPlaylistModified(mrh.playlists.Delete(args[0]))
+	case "playlistadd":
+		if err := checkMpdArgumentCount(command, args, 2, 2); err != nil {
+			return "", err
+		}
+		return "", mrh.storedPlaylistModified(mrh.playlists.AddEntry(args[0], args[1]))
+	case "playlistdelete":
+		if err := checkMpdArgumentCount(command, args, 2, 2); err != nil {
+			return "", err
+		}
+		position, err := parseMpdInteger(args[1])
+		if err != nil {
+			return "", err
+		}
+		return "", mrh.storedPlaylistModified(mrh.playlists.DeleteEntry(args[0], position))
+	case "playlistclear":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		return "", mrh.storedPlaylistModified(mrh.playlists.Clear(args[0]))
+	default:
+		return "", newMpdAckError(ACK_ERROR_UNKNOWN, "unknown command \"%s\"", command)
+	}
+}
+
+// appends the playlist in args[0] to the queue, only the entries in the range args[1] when given
+func (mrh *MpdRequestsHandler) loadStoredPlaylist(args []string) error {
+	entries, err := mrh.playlists.Load(args[0])
+	if err != nil {
+		return wrapStoredPlaylistError(err)
+	}
+	if len(args) > 1 {
+		start, end, err := parseQueueRange(args[1], len(entries))
+		if err != nil {
+			return newMpdAckError(ACK_ERROR_ARG, "%s", err.Error())
+		}
+		if start < 0 || start > len(entries) || end > len(entries) || start > end {
+			return newMpdAckError(ACK_ERROR_ARG, "Bad song index")
+		}
+		entries = entries[start:end]
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := mrh.playbackManager.AddEntriesToQueue(entries...); err != nil {
+		return newMpdAckError(ACK_ERROR_PLAYLIST_LOAD, "%s", err.Error())
+	}
+	return nil
+}
+
+// notifies idle clients when a stored playlist was changed successfully
+func (mrh *MpdRequestsHandler) storedPlaylistModified(err error) error {
+	if err != nil {
+		return wrapStoredPlaylistError(err)
+	}
+	mrh.partitions.NotifyAll(idle.SubsystemStoredPlaylist)
+	return nil
+}
+
+func wrapStoredPlaylistError(err error) error {
+	switch {
+	case errors.Is(err, playlist.ErrNotFound):
+		return newMpdAckError(ACK_ERROR_NO_EXIST, "No such playlist")
+	case errors.Is(err, playlist.ErrExists):
+		return newMpdAckError(ACK_ERROR_EXIST, "Playlist already exists")
+	case errors.Is(err, playlist.ErrInvalidName):
+		return newMpdAckError(ACK_ERROR_ARG, "%s", err.Error())
+	default:
+		return newMpdAckError(ACK_ERROR_SYSTEM, "%s", err.Error())
+	}
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+// handles the MPD commands which inspect or edit the playback queue
+func (mrh *MpdRequestsHandler) handleQueueCommand(command string, args []string) (string, error) {
+	switch command {
+	case "add":
+		if err := checkMpdArgumentCount(command, args, 1, 2); err != nil {
+			return "", err
+		}
+		_, err := mrh.addToQueue(args, true)
+		return "", err
+	case "addid":
+		if err := checkMpdArgumentCount(command, args, 1, 2); err != nil {
+			return "", err
+		}
+		ids, err := mrh.addToQueue(args, false)
+		if err != nil {
+			return "", err
+		}
+		response := &mpdResponseBuilder{}
+		response.add("Id", ids[0])
+		return response.String(), nil
+	case "delete":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		start, end, err := mrh.parseRange(args[0])
+		if err != nil {
+			return "", err
+		}
+		return "", wrapQueueError(mrh.playbackManager.DeleteFromQueue(start, end))
+	case "deleteid":
+		if err := checkMpdArgumentCount(command, args, 1, 1); err != nil {
+			return "", err
+		}
+		id, err := parseMpdInteger(args[0])
+		if err != nil {
+			return "", err
+		}
+		return "", wrapQueueError(mrh.playbackManager.DeleteIdFromQueue(id))
+	case "move":
+		if err := checkMpdArgumentCount(command, args, 2, 2); err != nil {
+			return "", err
+		}
+		start, end, err := mrh.parseRange(args[0])
+		if err != nil {
+			return "", err
+		}
+		to, err := mrh.parsePosition(args[1])
+		if err != nil {
+			return "", err
+		}
+		return "", wrapQueueError(mrh.playbackManager.MoveInQueue(start, end, to))
+	case "moveid":
+		if err := checkMpdArgumentCount(command, args, 2, 2); err != nil {
+			return "", err
+		}
+		id, err := parseMpdInteger(args[0])
+		if err != nil {
+			return "", err
+		}
+		to, err := mrh.parsePosition(args[1])
+		if err != nil {
+			return "", err
+		}
+		return "", wrapQueueError(mrh.playbackManager.MoveIdInQueue(id, to))
+	case "swap", "swapid":
+		if err := checkMpdArgumentCount(command, args, 2, 2); err != nil {
+			return "", err
+		}
+		first, err := parseMpdInteger(args[0])
+		if err != nil {
+			return "", err
+		}
+		second, err := parseMpdInteger(args[1])
+		if err != nil {
+			return "", err
+		}
+		if command == "swapid" {
+			return "", wrapQueueError(mrh.playbackManager.SwapIdsInQueue(first, second))
+		}
+		return "", wrapQueueError(mrh.playbackManager.SwapInQueue(first, second))
+	case "clear":
+		return "", wrapQueueError(mrh.playbackManager.ClearQueue())
+	case "shuffle":
+		if err := checkMpdArgumentCount(command, args, 0, 1); err != nil {
+			return "", err
+		}
+		start, end := 0, mrh.playbackManager.GetStatus().QueueLength
+		if len(args) > 0 {
+			var err error
+			start, end, err = mrh.parseRange(args[0])
+			if err != nil {
+				return "", err
+			}
+		}
+		if start == end {
+			// shuffling an empty queue is not an error
+			return "", nil
+		}
+		return "", wrapQueueError(mrh.playbackManager.ShuffleQueue(start, end))
+	case "rangeid":
+		if err := checkMpdArgumentCount(command, args, 2, 2); err != nil {
+			return "", err
+		}
+		return mrh.setRange(args)
+	case "prio", "prioid":
+		if err := checkMpdArgumentCount(command, args, 2, math.MaxInt); err != nil {
+			return "", err
+		}
+		return "", mrh.setPriority(command, args)
+	case "playlistinfo":
+		if err := checkMpdArgumentCount(command, args, 0, 1); err != nil {
+			return "", err
+		}
+		return mrh.playlistInfo(args)
+	case "playlistid":
+		if err := checkMpdArgumentCount(command, args, 0, 1); err != nil {
+			return "", err
+		}
+		return mrh.playlistId(args)
+	default:
+		return "", newMpdAckError(ACK_ERROR_UNKNOWN, "unknown command \"%s\"", command)
+	}
+}
+
+// inserts the URI in args[0] at the position in args[1], or appends it. Like in MPD, add accepts directories
+// which are added recursively, while addid only accepts single files
+func (mrh *MpdRequestsHandler) addToQueue(args []string, recursive bool) ([]int, error) {
+	position := mrh.playbackManager.GetStatus().QueueLength
+	if len(args) > 1 {
+		var err error
+		position, err = mrh.parsePosition(args[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	entries := []playbackmanager.QueueEntry{playbackmanager.NewQueueEntry(args[0])}
+	if recursive {
+		files, rejected := mrh.queueAdder.finder.Expand(args[:1])
+		if len(rejected) > 0 {
+			return nil, newRejectedPathError(args[0])
+		}
+		entries = newQueueEntries(files)
+	}
+	ids, err := mrh.playbackManager.InsertEntriesToQueue(position, entries...)
+	if len(ids) == 0 {
+		return nil, newMpdAckError(ACK_ERROR_NO_EXIST, "%s", err.Error())
+	}
+	if err != nil {
+		log.Printf("added %d of %d files from %s, error: %v", len(ids), len(entries), args[0], err)
+	}
+	return ids, nil
+}
+
+func (mrh *MpdRequestsHandler) playlistInfo(args []string) (string, error) {
+	queue := mrh.playbackManager.GetQueue()
+	start, end := 0, len(queue)
+	if len(args) > 0 {
+		var err error
+		start, end, err = mrh.parseRange(args[0])
+		if err != nil {
+			return "", err
+		}
+		if start < 0 || start >= len(queue) || end > len(queue) || start >= end {
+			return "", newMpdAckError(ACK_ERROR_ARG, "Bad song index")
+		}
+	}
+	response := &mpdResponseBuilder{}
+	for position := start; position < end; position++ {
+		mrh.addMpdSong(response, queue[position], position)
+	}
+	return response.String(), nil
+}
+
+func (mrh *MpdRequestsHandler) playlistId(args []string) (string, error) {
+	if len(args) == 0 {
+		return mrh.playlistInfo(args)
+	}
+	id, err := parseMpdInteger(args[0])
+	if err != nil {
+		return "", err
+	}
+	position, err := mrh.playbackManager.FindQueuePositionById(id)
+	if err != nil {
+		return "", newMpdAckError(ACK_ERROR_NO_EXIST, "%s", err.Error())
+	}
+	return mrh.playlistInfo([]string{strconv.Itoa(position)})
+}
+
+// sets the range of seconds "START:END" of the entry with ID args[0] which is played, an empty range ":" plays the whole file
+func (mrh *MpdRequestsHandler) setRange(args []string) (string, error) {
+	id, err := parseMpdInteger(args[0])
+	if err != nil {
+		return "", err
+	}
+	startString, endString, found := strings.Cut(args[1], ":")
+	if !found {
+		return "", newMpdAckError(ACK_ERROR_ARG, "Range expected: %s", args[1])
+	}
+	if startString == "" && endString == "" {
+		return "", wrapQueueError(mrh.playbackManager.SetEntryRange(id, nil))
+	}
+	start, err := parseMpdSeconds(startString)
+	if err != nil {
+		return "", err
+	}
+	end, err := parseMpdSeconds(endString)
+	if err != nil {
+		return "", err
+	}
+	return "", wrapQueueError(mrh.playbackManager.SetEntryRange(id, &playbackmanager.TrackRange{Start: start, End: end}))
+}
+
+// sets the priority in args[0] of the queue ranges, or of the IDs for prioid, given in the remaining arguments
+func (mrh *MpdRequestsHandler) setPriority(command string, args []string) error {
+	priority, err := parseMpdInteger(args[0])
+	if err != nil {
+		return err
+	}
+	if priority < 0 || priority > playbackmanager.MaximumPriority {
+		return newMpdAckError(ACK_ERROR_ARG, "Priority out of range: %s", args[0])
+	}
+	if command == "prioid" {
+		ids := []int{}
+		for _, arg := range args[1:] {
+			id, err := parseMpdInteger(arg)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return wrapQueueError(mrh.playbackManager.SetPriorityIds(priority, ids...))
+	}
+	for _, arg := range args[1:] {
+		start, end, err := mrh.parseRange(arg)
+		if err != nil {
+			return err
+		}
+		err = mrh.playbackManager.SetPriority(priority, start, end)
+		if err != nil {
+			return wrapQueueError(err)
+		}
+	}
+	return nil
+}
+
+// parses a bound of a range in (fractional) seconds, an empty bound is 0
+func parseMpdSeconds(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, newMpdAckError(ACK_ERROR_ARG, "Number expected: %s", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (mrh *MpdRequestsHandler) parseRange(value string) (int, int, error) {
+	start, end, err := parseQueueRange(value, mrh.playbackManager.GetStatus().QueueLength)
+	if err != nil {
+		return 0, 0, newMpdAckError(ACK_ERROR_ARG, "%s", err.Error())
+	}
+	return start, end, nil
+}
+
+func (mrh *MpdRequestsHandler) parsePosition(value string) (int, error) {
+	position, err := parseQueuePosition(value, mrh.playbackManager.GetStatus().QueuePosition)
+	if err != nil {
+		return 0, newMpdAckError(ACK_ERROR_ARG, "%s", err.Error())
+	}
+	return position, nil
+}
+
+func wrapQueueError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return newMpdAckError(ACK_ERROR_ARG, "%s", err.Error())
+}
+
+// tells why no audio file was found for a path. Only paths which do not exist are reported as such, while errors
+// like missing permissions are passed on
+func newRejectedPathError(filePath string) error {
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return newMpdAckError(ACK_ERROR_NO_EXIST, "No such directory or file: %s", filePath)
+	}
+	if err != nil {
+		return newMpdAckError(ACK_ERROR_SYSTEM, "%s", err.Error())
+	}
+	if info.IsDir() {
+		if _, err := os.ReadDir(filePath); err != nil {
+			return newMpdAckError(ACK_ERROR_SYSTEM, "%s", err.Error())
+		}
+	}
+	return newMpdAckError(ACK_ERROR_ARG, "No audio files found in: %s", filePath)
+}
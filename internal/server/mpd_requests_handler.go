@@ -0,0 +1,681 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/database"
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+	"github.com/arpitpandey992/go-mpd/internal/playlist"
+	"github.com/arpitpandey992/go-mpd/internal/replaygain"
+)
+
+const MPD_PROTOCOL_VERSION = "0.23.5"
+
+// ACK error codes as defined by the MPD protocol
+const (
+	ACK_ERROR_NOT_LIST       = 1
+	ACK_ERROR_ARG            = 2
+	ACK_ERROR_PASSWORD       = 3
+	ACK_ERROR_PERMISSION     = 4
+	ACK_ERROR_UNKNOWN        = 5
+	ACK_ERROR_NO_EXIST       = 50
+	ACK_ERROR_PLAYLIST_MAX   = 51
+	ACK_ERROR_SYSTEM         = 52
+	ACK_ERROR_PLAYLIST_LOAD  = 53
+	ACK_ERROR_UPDATE_ALREADY = 54
+	ACK_ERROR_PLAYER_SYNC    = 55
+	ACK_ERROR_EXIST          = 56
+)
+
+type mpdAckError struct {
+	code    int
+	message string
+}
+
+func (e *mpdAckError) Error() string {
+	return e.message
+}
+
+func newMpdAckError(code int, format string, args ...any) *mpdAckError {
+	return &mpdAckError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// MpdRequestsHandler serves a single client connection speaking the MPD protocol.
+// It keeps the per-connection command list state along with the handlers for individual commands
+type MpdRequestsHandler struct {
+	partitions      *Partitions
+	partition       *Partition // controlled by the connection, the player and queue adder below belong to it
+	playbackManager *playbackmanager.PlaybackManager
+	queueAdder      *QueueAdder
+	database        *database.AudioMeilisearchClient
+	playlists       *playlist.Store // nil when stored playlists are not configured
+	output          io.Writer       // used for responses written outside of HandleMpdLine, like the end of idle
+
+	commandList        [][]string
+	inCommandList      bool
+	commandListOkBegin bool
+	closeRequested     bool
+
+	idleListener *idle.Listener
+	idleCancel   chan struct{} // non nil while the connection is idling
+	idleDone     chan struct{}
+}
+
+// creates a handler controlling the default partition
+func getNewMpdRequestsHandler(partitions *Partitions, db *database.AudioMeilisearchClient, playlists *playlist.Store, output io.Writer) *MpdRequestsHandler {
+	handler := &MpdRequestsHandler{
+		partitions: partitions,
+		database:   db,
+		playlists:  playlists,
+		output:     output,
+	}
+	handler.usePartition(partitions.acquireDefault())
+	return handler
+}
+
+// Close cancels a pending idle and releases the resources held for the connection
+func (mrh *MpdRequestsHandler) Close() {
+	if mrh.isIdle() {
+		mrh.stopIdle()
+	}
+	mrh.idleListener.Close()
+	mrh.partitions.release(mrh.partition)
+}
+
+// makes the connection control the partition, which was already acquired for it
+func (mrh *MpdRequestsHandler) usePartition(partition *Partition) {
+	if mrh.idleListener != nil {
+		mrh.idleListener.Close()
+		mrh.partitions.release(mrh.partition)
+	}
+	mrh.partition = partition
+	mrh.playbackManager, mrh.queueAdder = partition.playbackManager, partition.queueAdder
+	mrh.idleListener = partition.playbackManager.IdleNotifier().Listen()
+}
+
+// HandleMpdLine processes one line of client input and returns the complete response for it,
+// including the trailing OK/ACK. While a command list is being received, the response is empty.
+func (mrh *MpdRequestsHandler) HandleMpdLine(line string) string {
+	commands, err := tokenizeMpdCommand(line)
+	if err != nil {
+		return formatMpdAck(err, "", 0)
+	}
+	if len(commands) == 0 {
+		return formatMpdAck(newMpdAckError(ACK_ERROR_UNKNOWN, "No command given"), "", 0)
+	}
+	commandName := strings.ToLower(commands[0])
+
+	if mrh.isIdle() {
+		if commandName != "noidle" {
+			log.Printf("error: received %s while idling, closing connection", commandName)
+			mrh.closeRequested = true
+			return ""
+		}
+		mrh.stopIdle()
+		return ""
+	}
+
+	if mrh.inCommandList {
+		if commandName != "command_list_end" {
+			mrh.commandList = append(mrh.commandList, commands)
+			return ""
+		}
+		return mrh.executeCommandList()
+	}
+
+	switch commandName {
+	case "command_list_begin", "command_list_ok_begin":
+		mrh.inCommandList = true
+		mrh.commandListOkBegin = commandName == "command_list_ok_begin"
+		mrh.commandList = [][]string{}
+		return ""
+	case "command_list_end":
+		return formatMpdAck(newMpdAckError(ACK_ERROR_NOT_LIST, "not in command list mode"), commandName, 0)
+	case "idle":
+		err := mrh.startIdle(commands[1:])
+		if err != nil {
+			return formatMpdAck(err, commandName, 0)
+		}
+		return ""
+	case "noidle":
+		// not idling, nothing to cancel
+		return ""
+	}
+
+	response, err := mrh.HandleMpdRequest(commands)
+	if err != nil {
+		return formatMpdAck(err, commandName, 0)
+	}
+	return response + "OK\n"
+}
+
+func (mrh *MpdRequestsHandler) executeCommandList() string {
+	commandList := mrh.commandList
+	mrh.inCommandList = false
+	mrh.commandList = nil
+
+	var response strings.Builder
+	for i, commands := range commandList {
+		body, err := mrh.HandleMpdRequest(commands)
+		if err != nil {
+			response.WriteString(formatMpdAck(err, strings.ToLower(commands[0]), i))
+			return response.String()
+		}
+		response.WriteString(body)
+		if mrh.commandListOkBegin {
+			response.WriteString("list_OK\n")
+		}
+	}
+	response.WriteString("OK\n")
+	return response.String()
+}
+
+// HandleMpdRequest runs a single MPD command and returns its response body in "key: value" lines
+func (mrh *MpdRequestsHandler) HandleMpdRequest(commands []string) (string, error) {
+	mainCommand := strings.ToLower(commands[0])
+	args := commands[1:]
+	switch mainCommand {
+	case "ping":
+		return "", nil
+	case "close":
+		mrh.closeRequested = true
+		return "", nil
+	case "play":
+		return mrh.play(args)
+	case "pause":
+		return mrh.pause(args)
+	case "stop":
+		return mrh.stop()
+	case "next":
+		return "", wrapPlaybackError(mrh.playbackManager.Next())
+	case "previous":
+		return "", wrapPlaybackError(mrh.playbackManager.Previous())
+	case "playid":
+		return mrh.playId(args)
+	case "seekcur":
+		if err := checkMpdArgumentCount(mainCommand, args, 1, 1); err != nil {
+			return "", err
+		}
+		return mrh.seekCurrent(args[0])
+	case "seek", "seekid":
+		if err := checkMpdArgumentCount(mainCommand, args, 2, 2); err != nil {
+			return "", err
+		}
+		return mrh.seek(mainCommand, args)
+	case "add", "addid", "delete", "deleteid", "move", "moveid", "swap", "swapid", "clear", "playlistinfo", "playlistid", "rangeid", "prio", "prioid", "shuffle":
+		return mrh.handleQueueCommand(mainCommand, args)
+	case "repeat", "random", "single", "consume":
+		return mrh.setPlaybackOption(mainCommand, args)
+	case "crossfade":
+		return mrh.setCrossfade(args)
+	case "setvol", "volume", "getvol", "mute", "unmute":
+		return mrh.handleVolumeCommand(mainCommand, args)
+	case "save", "load", "listplaylists", "listplaylist", "listplaylistinfo", "rename", "rm", "playlistadd", "playlistdelete", "playlistclear":
+		return mrh.handleStoredPlaylistCommand(mainCommand, args)
+	case "partition", "listpartitions", "newpartition", "delpartition", "moveoutput", "outputs", "enableoutput", "disableoutput", "toggleoutput":
+		return mrh.handlePartitionCommand(mainCommand, args)
+	case "replay_gain_mode":
+		return mrh.setReplayGainMode(args)
+	case "replay_gain_status":
+		response := &mpdResponseBuilder{}
+		response.add("replay_gain_mode", mrh.playbackManager.GetReplayGainSettings().Mode)
+		return response.String(), nil
+	case "idle", "noidle":
+		return "", newMpdAckError(ACK_ERROR_ARG, "\"%s\" is not allowed in a command list", mainCommand)
+	case "status":
+		return mrh.status(), nil
+	case "currentsong":
+		return mrh.currentSong(), nil
+	default:
+		return "", newMpdAckError(ACK_ERROR_UNKNOWN, "unknown command \"%s\"", mainCommand)
+	}
+}
+
+// starts waiting for changes in the background. The response is written to the output once something changed
+// or noidle was received, no other command is accepted until then
+func (mrh *MpdRequestsHandler) startIdle(args []string) error {
+	subsystems := []idle.Subsystem{}
+	for _, arg := range args {
+		subsystem, err := idle.ParseSubsystem(strings.ToLower(arg))
+		if err != nil {
+			return newMpdAckError(ACK_ERROR_ARG, "%s", err.Error())
+		}
+		subsystems = append(subsystems, subsystem)
+	}
+	cancel, done := make(chan struct{}), make(chan struct{})
+	mrh.idleCancel, mrh.idleDone = cancel, done
+	go func() {
+		defer close(done)
+		response := &mpdResponseBuilder{}
+		for _, subsystem := range mrh.idleListener.Wait(subsystems, cancel) {
+			response.add("changed", subsystem)
+		}
+		_, err := mrh.output.Write([]byte(response.String() + "OK\n"))
+		if err != nil {
+			log.Printf("error: could not send idle response, error: %s", err)
+		}
+	}()
+	return nil
+}
+
+func (mrh *MpdRequestsHandler) isIdle() bool {
+	if mrh.idleDone == nil {
+		return false
+	}
+	select {
+	case <-mrh.idleDone:
+		mrh.idleCancel, mrh.idleDone = nil, nil
+		return false
+	default:
+		return true
+	}
+}
+
+// cancels the current idle and waits for its response to be written
+func (mrh *MpdRequestsHandler) stopIdle() {
+	close(mrh.idleCancel)
+	<-mrh.idleDone
+	mrh.idleCancel, mrh.idleDone = nil, nil
+}
+
+func (mrh *MpdRequestsHandler) play(args []string) (string, error) {
+	if len(args) > 0 {
+		position, err := parseMpdInteger(args[0])
+		if err != nil {
+			return "", err
+		}
+		return "", wrapPlaybackError(mrh.playbackManager.PlayQueuePosition(position))
+	}
+	if mrh.playbackManager.GetStatus().State == playbackmanager.PlaybackStatePlaying {
+		return "", nil
+	}
+	return "", wrapPlaybackError(mrh.playbackManager.Play())
+}
+
+func (mrh *MpdRequestsHandler) playId(args []string) (string, error) {
+	if len(args) == 0 {
+		return mrh.play(args)
+	}
+	id, err := parseMpdInteger(args[0])
+	if err != nil {
+		return "", err
+	}
+	return "", wrapPlaybackError(mrh.playbackManager.PlayId(id))
+}
+
+func (mrh *MpdRequestsHandler) pause(args []string) (string, error) {
+	state := mrh.playbackManager.GetStatus().State
+	if state == playbackmanager.PlaybackStateStopped {
+		return "", nil
+	}
+	shouldPause := state == playbackmanager.PlaybackStatePlaying
+	if len(args) > 0 {
+		switch args[0] {
+		case "0":
+			shouldPause = false
+		case "1":
+			shouldPause = true
+		default:
+			return "", newMpdAckError(ACK_ERROR_ARG, "Boolean (0/1) expected: %s", args[0])
+		}
+	}
+	if shouldPause && state == playbackmanager.PlaybackStatePlaying {
+		return "", wrapPlaybackError(mrh.playbackManager.Pause())
+	}
+	if !shouldPause && state == playbackmanager.PlaybackStatePaused {
+		return "", wrapPlaybackError(mrh.playbackManager.Play())
+	}
+	return "", nil
+}
+
+func (mrh *MpdRequestsHandler) stop() (string, error) {
+	if mrh.playbackManager.GetStatus().State == playbackmanager.PlaybackStateStopped {
+		return "", nil
+	}
+	return "", wrapPlaybackError(mrh.playbackManager.Stop())
+}
+
+func (mrh *MpdRequestsHandler) setPlaybackOption(option string, args []string) (string, error) {
+	if err := checkMpdArgumentCount(option, args, 1, 1); err != nil {
+		return "", err
+	}
+	var enabled bool
+	switch args[0] {
+	case "0":
+		enabled = false
+	case "1":
+		enabled = true
+	default:
+		return "", newMpdAckError(ACK_ERROR_ARG, "Boolean (0/1) expected: %s", args[0])
+	}
+	switch option {
+	case "repeat":
+		mrh.playbackManager.SetRepeat(enabled)
+	case "random":
+		mrh.playbackManager.SetRandom(enabled)
+	case "single":
+		mrh.playbackManager.SetSingle(enabled)
+	case "consume":
+		mrh.playbackManager.SetConsume(enabled)
+	}
+	return "", nil
+}
+
+func (mrh *MpdRequestsHandler) setCrossfade(args []string) (string, error) {
+	if err := checkMpdArgumentCount("crossfade", args, 1, 1); err != nil {
+		return "", err
+	}
+	seconds, err := parseMpdInteger(args[0])
+	if err != nil {
+		return "", err
+	}
+	if seconds < 0 {
+		return "", newMpdAckError(ACK_ERROR_ARG, "Number is negative: %s", args[0])
+	}
+	return "", mrh.playbackManager.SetCrossfade(time.Duration(seconds) * time.Second)
+}
+
+func (mrh *MpdRequestsHandler) handleVolumeCommand(command string, args []string) (string, error) {
+	maximumArgs := 0
+	if command == "setvol" || command == "volume" {
+		maximumArgs = 1
+	}
+	if err := checkMpdArgumentCount(command, args, maximumArgs, maximumArgs); err != nil {
+		return "", err
+	}
+	switch command {
+	case "setvol":
+		volume, err := parseMpdInteger(args[0])
+		if err != nil {
+			return "", err
+		}
+		if err := mrh.playbackManager.SetVolume(volume); err != nil {
+			return "", newMpdAckError(ACK_ERROR_ARG, "Invalid volume value")
+		}
+	case "volume":
+		delta, err := parseMpdInteger(args[0])
+		if err != nil {
+			return "", err
+		}
+		mrh.playbackManager.ChangeVolume(delta)
+	case "getvol":
+		response := &mpdResponseBuilder{}
+		response.add("volume", mpdVolume(mrh.playbackManager.GetStatus()))
+		return response.String(), nil
+	case "mute":
+		mrh.playbackManager.SetMuted(true)
+	case "unmute":
+		mrh.playbackManager.SetMuted(false)
+	}
+	return "", nil
+}
+
+func (mrh *MpdRequestsHandler) setReplayGainMode(args []string) (string, error) {
+	if err := checkMpdArgumentCount("replay_gain_mode", args, 1, 1); err != nil {
+		return "", err
+	}
+	mode, err := replaygain.ParseMode(args[0])
+	if err != nil {
+		return "", newMpdAckError(ACK_ERROR_ARG, "Unrecognized replay gain mode")
+	}
+	mrh.playbackManager.SetReplayGainMode(mode)
+	return "", nil
+}
+
+// seeks within the current song, a time prefixed with + or - is relative to the current position
+func (mrh *MpdRequestsHandler) seekCurrent(timeString string) (string, error) {
+	seconds, err := strconv.ParseFloat(timeString, 64)
+	if err != nil {
+		return "", newMpdAckError(ACK_ERROR_ARG, "Number expected: %s", timeString)
+	}
+	target := playbackmanager.SeekTarget{Mode: playbackmanager.SeekAbsolute, Time: time.Duration(seconds * float64(time.Second))}
+	if strings.HasPrefix(timeString, "+") || strings.HasPrefix(timeString, "-") {
+		target.Mode = playbackmanager.SeekRelative
+	}
+	return "", wrapPlaybackError(mrh.playbackManager.SeekCurrent(target))
+}
+
+// seeks within the song at the position or with the ID in args[0], which starts playing if it is not the current song
+func (mrh *MpdRequestsHandler) seek(command string, args []string) (string, error) {
+	song, err := parseMpdInteger(args[0])
+	if err != nil {
+		return "", err
+	}
+	seconds, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || seconds < 0 {
+		return "", newMpdAckError(ACK_ERROR_ARG, "Number expected: %s", args[1])
+	}
+	target := playbackmanager.SeekTarget{Mode: playbackmanager.SeekAbsolute, Time: time.Duration(seconds * float64(time.Second))}
+	if command == "seekid" {
+		return "", wrapPlaybackError(mrh.playbackManager.SeekId(song, target))
+	}
+	return "", wrapPlaybackError(mrh.playbackManager.SeekQueuePosition(song, target))
+}
+
+func (mrh *MpdRequestsHandler) status() string {
+	status := mrh.playbackManager.GetStatus()
+	response := &mpdResponseBuilder{}
+	response.add("volume", mpdVolume(status))
+	response.add("repeat", mpdBoolean(status.Options.Repeat))
+	response.add("random", mpdBoolean(status.Options.Random))
+	response.add("single", mpdBoolean(status.Options.Single))
+	response.add("consume", mpdBoolean(status.Options.Consume))
+	response.add("partition", mrh.partition.Name)
+	if status.Options.Crossfade > 0 {
+		response.add("xfade", int(status.Options.Crossfade.Seconds()))
+	}
+	response.add("playlist", status.QueueVersion)
+	response.add("playlistlength", status.QueueLength)
+	response.add("state", mpdPlaybackState(status.State))
+	if status.QueuePosition < status.QueueLength {
+		response.add("song", status.QueuePosition)
+		response.add("songid", status.CurrentId)
+	}
+	if status.State != playbackmanager.PlaybackStateStopped {
+		response.add("time", fmt.Sprintf("%d:%d", int(status.Elapsed.Seconds()), int(status.Duration.Seconds())))
+		response.add("elapsed", fmt.Sprintf("%.3f", status.Elapsed.Seconds()))
+		response.add("duration", fmt.Sprintf("%.3f", status.Duration.Seconds()))
+		response.add("audio", fmt.Sprintf("%d:%d:%d", status.SampleRate, status.BitDepth, status.Channels))
+	}
+	return response.String()
+}
+
+func (mrh *MpdRequestsHandler) currentSong() string {
+	// the queue and the position are read together, so another client changing the queue can not mix them up
+	snapshot := mrh.playbackManager.GetSnapshot()
+	if snapshot.QueuePosition >= len(snapshot.Queue) {
+		return ""
+	}
+	response := &mpdResponseBuilder{}
+	mrh.addMpdSong(response, snapshot.Queue[snapshot.QueuePosition], snapshot.QueuePosition)
+	if snapshot.State != playbackmanager.PlaybackStateStopped {
+		response.add("duration", fmt.Sprintf("%.3f", snapshot.Duration.Seconds()))
+	}
+	return response.String()
+}
+
+// writes the file, range, tags, position and ID of a queue entry. The tags are the ones cached when the entry was added
+func (mrh *MpdRequestsHandler) addMpdSong(response *mpdResponseBuilder, entry playbackmanager.QueueEntry, position int) {
+	response.add("file", entry.URI)
+	if entry.Range != nil {
+		response.add("Range", formatMpdRange(*entry.Range))
+	}
+	if entry.Metadata != nil {
+		addMpdSongTags(response, entry.Metadata)
+	}
+	response.add("Pos", position)
+	response.add("Id", entry.ID)
+	if entry.Priority > 0 {
+		response.add("Prio", entry.Priority)
+	}
+}
+
+// adds the file and the tags found in the database, which is everything known about a song outside of the queue
+func (mrh *MpdRequestsHandler) addMpdSongFile(response *mpdResponseBuilder, filePath string) {
+	response.add("file", filePath)
+	metadata, err := findAudioFileMetadata(mrh.database, filePath)
+	if err != nil {
+		log.Printf("could not look up metadata for %s, error: %v", filePath, err)
+	}
+	if metadata != nil {
+		addMpdSongTags(response, metadata)
+	}
+}
+
+func addMpdSongTags(response *mpdResponseBuilder, metadata *database.AudioFileMetadata) {
+	listTags := []struct {
+		name   string
+		values []string
+	}{
+		{"Title", metadata.Title},
+		{"Artist", metadata.Artist},
+		{"Album", metadata.Album},
+		{"AlbumArtist", metadata.AlbumArtist},
+		{"Comment", metadata.Comment},
+	}
+	for _, tag := range listTags {
+		for _, value := range tag.values {
+			response.add(tag.name, value)
+		}
+	}
+	optionalTags := []struct {
+		name  string
+		value *string
+	}{
+		{"Date", metadata.Date},
+		{"Genre", metadata.Genre},
+		{"Composer", metadata.Composer},
+		{"Performer", metadata.Performer},
+		{"Conductor", metadata.Conductor},
+	}
+	for _, tag := range optionalTags {
+		if tag.value != nil {
+			response.add(tag.name, *tag.value)
+		}
+	}
+	if metadata.TrackNumber != nil {
+		response.add("Track", *metadata.TrackNumber)
+	}
+	if metadata.DiscNumber != nil {
+		response.add("Disc", *metadata.DiscNumber)
+	}
+}
+
+type mpdResponseBuilder struct {
+	builder strings.Builder
+}
+
+func (rb *mpdResponseBuilder) add(key string, value any) {
+	rb.builder.WriteString(fmt.Sprintf("%s: %v\n", key, value))
+}
+
+func (rb *mpdResponseBuilder) String() string {
+	return rb.builder.String()
+}
+
+func mpdPlaybackState(state playbackmanager.PlaybackState) string {
+	switch state {
+	case playbackmanager.PlaybackStatePlaying:
+		return "play"
+	case playbackmanager.PlaybackStatePaused:
+		return "pause"
+	default:
+		return "stop"
+	}
+}
+
+func checkMpdArgumentCount(command string, args []string, minimum, maximum int) error {
+	if len(args) < minimum || len(args) > maximum {
+		return newMpdAckError(ACK_ERROR_ARG, "wrong number of arguments for \"%s\"", command)
+	}
+	return nil
+}
+
+func mpdBoolean(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+// the volume which is audible, a muted output is reported as silent
+func mpdVolume(status playbackmanager.PlaybackStatus) int {
+	if status.Muted {
+		return 0
+	}
+	return status.Volume
+}
+
+// formats a range as "START-END" in seconds, the end is left out for ranges which play until the end of the file
+func formatMpdRange(trackRange playbackmanager.TrackRange) string {
+	formatted := fmt.Sprintf("%.3f-", trackRange.Start.Seconds())
+	if trackRange.End > 0 {
+		formatted += fmt.Sprintf("%.3f", trackRange.End.Seconds())
+	}
+	return formatted
+}
+
+func parseMpdInteger(value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, newMpdAckError(ACK_ERROR_ARG, "Integer expected: %s", value)
+	}
+	return number, nil
+}
+
+func wrapPlaybackError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return newMpdAckError(ACK_ERROR_PLAYER_SYNC, "%s", err.Error())
+}
+
+func formatMpdAck(err error, commandName string, commandListIndex int) string {
+	code := ACK_ERROR_SYSTEM
+	if ackErr, ok := err.(*mpdAckError); ok {
+		code = ackErr.code
+	}
+	return fmt.Sprintf("ACK [%d@%d] {%s} %s\n", code, commandListIndex, commandName, err.Error())
+}
+
+// splits an MPD command line into its arguments, honouring double quotes and backslash escapes inside them
+func tokenizeMpdCommand(line string) ([]string, error) {
+	tokens := []string{}
+	i, n := 0, len(line)
+	for i < n {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		if line[i] != '"' {
+			j := i
+			for j < n && line[j] != ' ' && line[j] != '\t' {
+				j++
+			}
+			tokens = append(tokens, line[i:j])
+			i = j
+			continue
+		}
+		var token strings.Builder
+		j := i + 1
+		for j < n && line[j] != '"' {
+			if line[j] == '\\' && j+1 < n {
+				j++
+			}
+			token.WriteByte(line[j])
+			j++
+		}
+		if j == n {
+			return nil, newMpdAckError(ACK_ERROR_ARG, "Missing closing '\"'")
+		}
+		tokens = append(tokens, token.String())
+		i = j + 1
+	}
+	return tokens, nil
+}
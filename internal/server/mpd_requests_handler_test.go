@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/playlist"
+)
+
+func TestTokenizeMpdCommand(t *testing.T) {
+	tokens, err := tokenizeMpdCommand(`add "music/Some \"Quoted\" Album/01 track.flac"`)
+	checkError(err, t)
+	expected := []string{"add", `music/Some "Quoted" Album/01 track.flac`}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("expected: %q, got: %q", expected, tokens)
+	}
+	_, err = tokenizeMpdCommand(`add "unterminated`)
+	if err == nil {
+		t.Error("expected an error for a missing closing quote")
+	}
+}
+
+func TestMpdCommandList(t *testing.T) {
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	for _, line := range []string{"command_list_ok_begin", "ping", "status"} {
+		if response := handler.HandleMpdLine(line); response != "" {
+			t.Errorf("expected no response inside a command list, got: %q", response)
+		}
+	}
+	response := handler.HandleMpdLine("command_list_end")
+	if !strings.HasPrefix(response, "list_OK\n") || !strings.Contains(response, "\nstate: ") || !strings.HasSuffix(response, "\nlist_OK\nOK\n") {
+		t.Errorf("unexpected command list response: %q", response)
+	}
+
+	handler.HandleMpdLine("command_list_begin")
+	handler.HandleMpdLine("ping")
+	handler.HandleMpdLine("foo")
+	response = handler.HandleMpdLine("command_list_end")
+	expected := "ACK [5@1] {foo} unknown command \"foo\"\n"
+	if response != expected {
+		t.Errorf("expected: %q, got: %q", expected, response)
+	}
+}
+
+func TestMpdIdle(t *testing.T) {
+	output := &bytes.Buffer{}
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, output)
+	defer handler.Close()
+
+	if response := handler.HandleMpdLine("idle mixer"); response != "" {
+		t.Fatalf("expected idle to respond asynchronously, got: %q", response)
+	}
+	server.playbackManager.IdleNotifier().Notify(idle.SubsystemMixer)
+	<-handler.idleDone
+	if output.String() != "changed: mixer\nOK\n" {
+		t.Errorf("unexpected idle response: %q", output.String())
+	}
+
+	output.Reset()
+	handler.HandleMpdLine("idle")
+	handler.HandleMpdLine("noidle")
+	if output.String() != "OK\n" {
+		t.Errorf("unexpected noidle response: %q", output.String())
+	}
+
+	response := handler.HandleMpdLine("idle foo")
+	if response != "ACK [2@0] {idle} unrecognized idle event: foo\n" {
+		t.Errorf("unexpected response for an unknown subsystem: %q", response)
+	}
+}
+
+func TestMpdCrossfade(t *testing.T) {
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	defer handler.HandleMpdLine("crossfade 0")
+
+	if response := handler.HandleMpdLine("crossfade 3"); response != "OK\n" {
+		t.Fatalf("unexpected crossfade response: %q", response)
+	}
+	if response := handler.HandleMpdLine("status"); !strings.Contains(response, "\nxfade: 3\n") {
+		t.Errorf("crossfade missing from status: %q", response)
+	}
+	if response := handler.HandleMpdLine("crossfade -1"); !strings.HasPrefix(response, "ACK [2@0] {crossfade}") {
+		t.Errorf("expected an error for a negative crossfade, got: %q", response)
+	}
+}
+
+func TestMpdVolume(t *testing.T) {
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	defer handler.HandleMpdLine("setvol 100")
+
+	handler.HandleMpdLine("setvol 40")
+	handler.HandleMpdLine("volume -15")
+	if response := handler.HandleMpdLine("getvol"); response != "volume: 25\nOK\n" {
+		t.Errorf("unexpected volume after a relative change: %q", response)
+	}
+	handler.HandleMpdLine("mute")
+	if response := handler.HandleMpdLine("status"); !strings.HasPrefix(response, "volume: 0\n") {
+		t.Errorf("expected a muted output to report no volume: %q", response)
+	}
+	handler.HandleMpdLine("unmute")
+	if response := handler.HandleMpdLine("setvol 101"); !strings.HasPrefix(response, "ACK [2@0] {setvol}") {
+		t.Errorf("expected an error for an out of range volume, got: %q", response)
+	}
+}
+
+func TestMpdReplayGainMode(t *testing.T) {
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	defer handler.HandleMpdLine("replay_gain_mode off")
+
+	handler.HandleMpdLine("replay_gain_mode album")
+	if response := handler.HandleMpdLine("replay_gain_status"); response != "replay_gain_mode: album\nOK\n" {
+		t.Errorf("unexpected replay gain status: %q", response)
+	}
+	if response := handler.HandleMpdLine("replay_gain_mode loud"); !strings.HasPrefix(response, "ACK [2@0] {replay_gain_mode}") {
+		t.Errorf("expected an error for an unknown mode, got: %q", response)
+	}
+}
+
+func TestMpdStoredPlaylists(t *testing.T) {
+	store := playlist.NewStore(t.TempDir(), []string{"../../music"})
+	handler := getNewMpdRequestsHandler(server.partitions, nil, store, io.Discard)
+
+	handler.HandleMpdLine("playlistadd mix ../../music/sample-3s.mp3")
+	handler.HandleMpdLine("playlistadd mix ../../music/sample-9s.mp3")
+	// the entries are stored relative to the scan directory and resolved against it again
+	expected := "file: ../../music/sample-3s.mp3\nfile: ../../music/sample-9s.mp3\nOK\n"
+	if response := handler.HandleMpdLine("listplaylist mix"); response != expected {
+		t.Errorf("unexpected playlist contents: %q", response)
+	}
+	if response := handler.HandleMpdLine("rename mix road"); response != "OK\n" {
+		t.Errorf("expected the rename to succeed, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("listplaylist mix"); !strings.HasPrefix(response, "ACK [50@0] {listplaylist}") {
+		t.Errorf("expected the old name to be gone, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("playlistdelete road 0"); response != "OK\n" {
+		t.Errorf("expected the entry to be removed, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("listplaylists"); !strings.HasPrefix(response, "playlist: road\nLast-Modified: ") {
+		t.Errorf("unexpected playlist listing: %q", response)
+	}
+	if response := handler.HandleMpdLine("rm road"); response != "OK\n" {
+		t.Errorf("expected the playlist to be deleted, got: %q", response)
+	}
+
+	withoutStore := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	if response := withoutStore.HandleMpdLine("listplaylists"); !strings.HasPrefix(response, "ACK [52@0] {listplaylists}") {
+		t.Errorf("expected an error without a playlist directory, got: %q", response)
+	}
+}
+
+func TestMpdRangeId(t *testing.T) {
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	response := handler.HandleMpdLine("addid ../../music/sample-9s.mp3")
+	id := strings.TrimSuffix(strings.TrimPrefix(response, "Id: "), "\nOK\n")
+	defer handler.HandleMpdLine("deleteid " + id)
+
+	if response := handler.HandleMpdLine("rangeid " + id + " 1.5:3"); response != "OK\n" {
+		t.Fatalf("expected the range to be set, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("playlistid " + id); !strings.Contains(response, "Range: 1.500-3.000\n") {
+		t.Errorf("expected the range in the song info, got: %q", response)
+	}
+	// the range is kept by stored playlists
+	store := playlist.NewStore(t.TempDir(), []string{"../../music"})
+	withStore := getNewMpdRequestsHandler(server.partitions, nil, store, io.Discard)
+	defer withStore.Close()
+	withStore.HandleMpdLine("save ranged")
+	if response := withStore.HandleMpdLine("listplaylist ranged"); !strings.Contains(response, "sample-9s.mp3\nRange: 1.500-3.000\n") {
+		t.Errorf("expected the range in the stored playlist, got: %q", response)
+	}
+	handler.HandleMpdLine("rangeid " + id + " :")
+	if response := handler.HandleMpdLine("playlistid " + id); strings.Contains(response, "Range:") {
+		t.Errorf("expected the range to be removed, got: %q", response)
+	}
+}
+
+func TestMpdPrioId(t *testing.T) {
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	response := handler.HandleMpdLine("addid ../../music/sample-9s.mp3")
+	id := strings.TrimSuffix(strings.TrimPrefix(response, "Id: "), "\nOK\n")
+	defer handler.HandleMpdLine("deleteid " + id)
+
+	if response := handler.HandleMpdLine("prioid 200 " + id); response != "OK\n" {
+		t.Fatalf("expected the priority to be set, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("playlistid " + id); !strings.Contains(response, "Prio: 200\n") {
+		t.Errorf("expected the priority in the song info, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("prioid 256 " + id); !strings.HasPrefix(response, "ACK [2@0] {prioid}") {
+		t.Errorf("expected an error for a priority out of range, got: %q", response)
+	}
+	handler.HandleMpdLine("prioid 0 " + id)
+	if response := handler.HandleMpdLine("playlistid " + id); strings.Contains(response, "Prio:") {
+		t.Errorf("expected the priority to be removed, got: %q", response)
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PartitionRequestsHandler keeps track of the partition a connection controls, and manages the partitions and their outputs
+type PartitionRequestsHandler struct {
+	partitions *Partitions
+	partition  *Partition
+	onSwitch   func(partition *Partition) // points the other handlers of the connection at the partition
+}
+
+// creates a handler controlling the default partition, onSwitch is called right away and on every switch to another partition
+func getNewPartitionRequestsHandler(partitions *Partitions, onSwitch func(partition *Partition)) *PartitionRequestsHandler {
+	handler := &PartitionRequestsHandler{
+		partitions: partitions,
+		partition:  partitions.acquireDefault(),
+		onSwitch:   onSwitch,
+	}
+	onSwitch(handler.partition)
+	return handler
+}
+
+// Close releases the partition once the connection is closed
+func (prh *PartitionRequestsHandler) Close() {
+	prh.partitions.release(prh.partition)
+}
+
+func (prh *PartitionRequestsHandler) HandlePartitionRequest(commands []string) (string, error) {
+	mainCommand := strings.ToLower(commands[0])
+	switch mainCommand {
+	case "current":
+		if err := expectArguments(commands, 0, 0); err != nil {
+			return "", err
+		}
+		return prh.partition.Name, nil
+	case "use":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		partition, err := prh.partitions.acquire(commands[1])
+		if err != nil {
+			return "", err
+		}
+		prh.partitions.release(prh.partition)
+		prh.partition = partition
+		prh.onSwitch(partition)
+		return fmt.Sprintf("controlling partition %s", partition.Name), nil
+	case "list":
+		if err := expectArguments(commands, 0, 0); err != nil {
+			return "", err
+		}
+		lines := []string{}
+		for _, name := range prh.partitions.Names() {
+			marker := " "
+			if name == prh.partition.Name {
+				marker = "*"
+			}
+			lines = append(lines, marker+name)
+		}
+		return strings.Join(lines, "\n"), nil
+	case "create":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		if err := prh.partitions.Create(commands[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("created partition %s, move an output to it to hear it", commands[1]), nil
+	case "delete":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		if err := prh.partitions.Delete(commands[1]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("deleted partition %s", commands[1]), nil
+	case "outputs":
+		if err := expectArguments(commands, 0, 0); err != nil {
+			return "", err
+		}
+		return prh.listOutputs(), nil
+	case "moveoutput":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		if err := prh.partitions.MoveOutput(commands[1], prh.partition.Name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("moved output %s to partition %s", commands[1], prh.partition.Name), nil
+	case "enable", "disable", "toggle":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return prh.setOutputEnabled(mainCommand, commands[1])
+	default:
+		return "", fmt.Errorf("unknown partition command: %s", mainCommand)
+	}
+}
+
+func (prh *PartitionRequestsHandler) listOutputs() string {
+	outputs := prh.partitions.Outputs(prh.partition.Name)
+	if len(outputs) == 0 {
+		return fmt.Sprintf("partition %s has no outputs", prh.partition.Name)
+	}
+	lines := []string{}
+	for _, output := range outputs {
+		state := "disabled"
+		if output.Enabled {
+			state = "enabled"
+		}
+		lines = append(lines, fmt.Sprintf("%d: %s (%s)", output.ID, output.Name, state))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (prh *PartitionRequestsHandler) setOutputEnabled(command, idString string) (string, error) {
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		return "", fmt.Errorf("invalid output id: %s", idString)
+	}
+	var enabled *bool // toggle
+	if command != "toggle" {
+		enable := command == "enable"
+		enabled = &enable
+	}
+	err = prh.partitions.SetOutputEnabled(prh.partition.Name, id, enabled)
+	if err != nil {
+		return "", err
+	}
+	return prh.listOutputs(), nil
+}
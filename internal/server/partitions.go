@@ -0,0 +1,261 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+const (
+	DEFAULT_PARTITION_NAME = "default"
+	DEFAULT_OUTPUT_NAME    = "default" // the audio device, which is the only output
+)
+
+var partitionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Partition is a player with its own queue, playback options and outputs, controlled independently of the other partitions.
+// Every connection starts out controlling the default partition and can switch to another one. There is a single audio
+// device, so only one partition can be heard at a time; the others keep playing silently and their listens are not recorded
+type Partition struct {
+	Name            string
+	playbackManager *playbackmanager.PlaybackManager
+	queueAdder      *QueueAdder
+	autoDJ          *AutoDJ // nil when there is no audio database to choose tracks from
+	stopListens     func()  // stops recording the listens of the partition, nil when they are not recorded
+	clients         int     // connections controlling the partition, it can only be deleted when there are none
+}
+
+// stops the auto-DJ of the partition and the recording of its listens
+func (partition *Partition) stopWorkers() {
+	if partition.autoDJ != nil {
+		partition.autoDJ.Close()
+	}
+	if partition.stopListens != nil {
+		partition.stopListens()
+	}
+}
+
+// stops the player of a deleted partition along with its workers
+func (partition *Partition) close() {
+	partition.stopWorkers()
+	partition.playbackManager.Close()
+}
+
+// Output is the audio device, which plays the audio of the partition it is assigned to. Every partition has its own mixer
+// in front of the device, and only the one with the enabled output is heard
+type Output struct {
+	ID        int
+	Name      string
+	Enabled   bool
+	Partition string
+}
+
+// Partitions keeps the partitions of the server along with the outputs assigned to them
+type Partitions struct {
+	partitions   map[string]*Partition
+	outputs      []*Output
+	newPartition func(name string) *Partition // creates a partition with a player configured like the default one
+	lock         sync.Mutex
+}
+
+func newPartitions(defaultPartition *Partition, newPartition func(name string) *Partition) *Partitions {
+	return &Partitions{
+		partitions:   map[string]*Partition{defaultPartition.Name: defaultPartition},
+		outputs:      []*Output{{ID: 0, Name: DEFAULT_OUTPUT_NAME, Enabled: true, Partition: defaultPartition.Name}},
+		newPartition: newPartition,
+	}
+}
+
+// Create adds a new partition, which is silent until the output is moved to it
+func (partitions *Partitions) Create(name string) error {
+	_, err := partitions.create(name)
+	return err
+}
+
+func (partitions *Partitions) create(name string) (*Partition, error) {
+	if !partitionNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid partition name: %s, expected letters, digits, '_' or '-'", name)
+	}
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	if _, exists := partitions.partitions[name]; exists {
+		return nil, fmt.Errorf("partition already exists: %s", name)
+	}
+	partition := partitions.newPartition(name)
+	partition.playbackManager.SetAudible(false)
+	partitions.partitions[name] = partition
+	log.Printf("created partition: %s", name)
+	partitions.notifyAll(idle.SubsystemPartition)
+	return partition, nil
+}
+
+// Delete removes a partition which no connection controls anymore, the output goes back to the default partition if it had it
+func (partitions *Partitions) Delete(name string) error {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	partition, exists := partitions.partitions[name]
+	if !exists {
+		return fmt.Errorf("no such partition: %s", name)
+	}
+	if name == DEFAULT_PARTITION_NAME {
+		return fmt.Errorf("the default partition can not be deleted")
+	}
+	if partition.clients > 0 {
+		return fmt.Errorf("partition %s is in use by %d connections", name, partition.clients)
+	}
+	for _, output := range partitions.outputs {
+		if output.Partition == name {
+			output.Partition = DEFAULT_PARTITION_NAME
+		}
+	}
+	delete(partitions.partitions, name)
+	partition.close()
+	partitions.updateAudibility()
+	log.Printf("deleted partition: %s", name)
+	partitions.notifyAll(idle.SubsystemPartition, idle.SubsystemOutput)
+	return nil
+}
+
+// Names returns the names of the partitions, the default one first
+func (partitions *Partitions) Names() []string {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	names := []string{}
+	for name := range partitions.partitions {
+		if name != DEFAULT_PARTITION_NAME {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DEFAULT_PARTITION_NAME}, names...)
+}
+
+// returns the partitions, the default one first
+func (partitions *Partitions) all() []*Partition {
+	names := partitions.Names()
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	all := []*Partition{}
+	for _, name := range names {
+		if partition, exists := partitions.partitions[name]; exists {
+			all = append(all, partition)
+		}
+	}
+	return all
+}
+
+// acquire returns the partition for a connection which starts controlling it, release has to be called once it stops
+func (partitions *Partitions) acquire(name string) (*Partition, error) {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	partition, exists := partitions.partitions[name]
+	if !exists {
+		return nil, fmt.Errorf("no such partition: %s", name)
+	}
+	partition.clients++
+	return partition, nil
+}
+
+// acquireDefault is acquire for the default partition, which always exists
+func (partitions *Partitions) acquireDefault() *Partition {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	partition := partitions.partitions[DEFAULT_PARTITION_NAME]
+	partition.clients++
+	return partition
+}
+
+func (partitions *Partitions) release(partition *Partition) {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	partition.clients--
+}
+
+// Outputs returns a copy of the outputs assigned to the partition
+func (partitions *Partitions) Outputs(partitionName string) []Output {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	outputs := []Output{}
+	for _, output := range partitions.outputs {
+		if output.Partition == partitionName {
+			outputs = append(outputs, *output)
+		}
+	}
+	return outputs
+}
+
+// SetOutputEnabled enables or disables an output of the partition, toggling it when enabled is nil
+func (partitions *Partitions) SetOutputEnabled(partitionName string, id int, enabled *bool) error {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	if id < 0 || id >= len(partitions.outputs) || partitions.outputs[id].Partition != partitionName {
+		return fmt.Errorf("no such output: %d", id)
+	}
+	output := partitions.outputs[id]
+	if enabled == nil {
+		output.Enabled = !output.Enabled
+	} else {
+		output.Enabled = *enabled
+	}
+	partitions.updateAudibility()
+	partitions.notifyAll(idle.SubsystemOutput)
+	return nil
+}
+
+// MoveOutput assigns the output with the given name to the partition, taking it away from the one it was assigned to.
+// The audio device is the only output, so moving it makes this partition the only one which can be heard
+func (partitions *Partitions) MoveOutput(outputName, partitionName string) error {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	if _, exists := partitions.partitions[partitionName]; !exists {
+		return fmt.Errorf("no such partition: %s", partitionName)
+	}
+	for _, output := range partitions.outputs {
+		if output.Name == outputName {
+			output.Partition = partitionName
+			partitions.updateAudibility()
+			log.Printf("moved output %s to partition %s", outputName, partitionName)
+			partitions.notifyAll(idle.SubsystemOutput)
+			return nil
+		}
+	}
+	return fmt.Errorf("no such output: %s", outputName)
+}
+
+// Close stops the workers of the partitions, the players are left alone
+func (partitions *Partitions) Close() {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	for _, partition := range partitions.partitions {
+		partition.stopWorkers()
+	}
+}
+
+// NotifyAll notifies the clients of every partition, for changes which are not limited to a single partition
+func (partitions *Partitions) NotifyAll(subsystems ...idle.Subsystem) {
+	partitions.lock.Lock()
+	defer partitions.lock.Unlock()
+	partitions.notifyAll(subsystems...)
+}
+
+func (partitions *Partitions) notifyAll(subsystems ...idle.Subsystem) {
+	for _, partition := range partitions.partitions {
+		partition.playbackManager.IdleNotifier().Notify(subsystems...)
+	}
+}
+
+// only partitions with an enabled output are heard, must be called with the lock held
+func (partitions *Partitions) updateAudibility() {
+	audible := map[string]bool{}
+	for _, output := range partitions.outputs {
+		audible[output.Partition] = audible[output.Partition] || output.Enabled
+	}
+	for name, partition := range partitions.partitions {
+		partition.playbackManager.SetAudible(audible[name])
+	}
+}
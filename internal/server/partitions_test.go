@@ -0,0 +1,101 @@
+package server
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+	"github.com/arpitpandey992/go-mpd/internal/replaygain"
+)
+
+func TestMpdPartitions(t *testing.T) {
+	living := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	study := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	defer living.Close()
+
+	if response := study.HandleMpdLine("newpartition study"); response != "OK\n" {
+		t.Fatalf("expected the partition to be created, got: %q", response)
+	}
+	if response := study.HandleMpdLine("newpartition study"); !strings.HasPrefix(response, "ACK [56@0] {newpartition}") {
+		t.Errorf("expected an error for an existing partition, got: %q", response)
+	}
+	if response := study.HandleMpdLine("listpartitions"); response != "partition: default\npartition: study\nOK\n" {
+		t.Errorf("unexpected partitions: %q", response)
+	}
+	initialLength := server.playbackManager.GetStatus().QueueLength
+	study.HandleMpdLine("partition study")
+	if response := study.HandleMpdLine("status"); !strings.Contains(response, "partition: study\n") || !strings.Contains(response, "playlistlength: 0\n") {
+		t.Errorf("expected the empty queue of the new partition, got: %q", response)
+	}
+	study.HandleMpdLine("add ../../music/sample-3s.mp3")
+	if !strings.Contains(study.HandleMpdLine("status"), "playlistlength: 1\n") || server.playbackManager.GetStatus().QueueLength != initialLength {
+		t.Errorf("expected the file to be added to the new partition only")
+	}
+
+	// outputs belong to a single partition and the one without an enabled output is silent
+	if response := study.HandleMpdLine("outputs"); response != "OK\n" {
+		t.Errorf("expected no outputs in a new partition, got: %q", response)
+	}
+	study.HandleMpdLine("moveoutput " + DEFAULT_OUTPUT_NAME)
+	if response := study.HandleMpdLine("outputs"); !strings.Contains(response, "outputname: "+DEFAULT_OUTPUT_NAME+"\n") {
+		t.Errorf("expected the moved output, got: %q", response)
+	}
+	if response := living.HandleMpdLine("outputs"); response != "OK\n" {
+		t.Errorf("expected the output to be gone from the default partition, got: %q", response)
+	}
+	if response := study.HandleMpdLine("disableoutput 0"); response != "OK\n" || !strings.Contains(study.HandleMpdLine("outputs"), "outputenabled: 0\n") {
+		t.Errorf("expected the output to be disabled, got: %q", response)
+	}
+	study.HandleMpdLine("enableoutput 0")
+
+	if response := living.HandleMpdLine("delpartition study"); !strings.HasPrefix(response, "ACK [2@0] {delpartition}") {
+		t.Errorf("expected a partition in use not to be deleted, got: %q", response)
+	}
+	study.Close()
+	if response := living.HandleMpdLine("delpartition study"); response != "OK\n" {
+		t.Fatalf("expected the partition to be deleted, got: %q", response)
+	}
+	if response := living.HandleMpdLine("outputs"); !strings.Contains(response, "outputname: "+DEFAULT_OUTPUT_NAME+"\n") {
+		t.Errorf("expected the output to go back to the default partition, got: %q", response)
+	}
+	if response := living.HandleMpdLine("delpartition default"); !strings.HasPrefix(response, "ACK [2@0] {delpartition}") {
+		t.Errorf("expected the default partition not to be deleted, got: %q", response)
+	}
+	if response := living.HandleMpdLine("partition study"); !strings.HasPrefix(response, "ACK [50@0] {partition}") {
+		t.Errorf("expected an error for a deleted partition, got: %q", response)
+	}
+}
+
+func TestPartitionsPlayerState(t *testing.T) {
+	stateFilePath := filepath.Join(t.TempDir(), "state.yml")
+	newServer := func() *Server {
+		newPartition := func(name string) *Partition {
+			return &Partition{Name: name, playbackManager: playbackmanager.CreatePlaybackManager()}
+		}
+		defaultPartition := newPartition(DEFAULT_PARTITION_NAME)
+		return &Server{playbackManager: defaultPartition.playbackManager, partitions: newPartitions(defaultPartition, newPartition), stateFilePath: stateFilePath}
+	}
+
+	saved := newServer()
+	checkError(saved.partitions.Create("study"), t)
+	study := saved.partitions.all()[1]
+	checkError(study.playbackManager.AddAudioFilesToQueue("../../music/sample-3s.mp3", "../../music/sample-9s.mp3"), t)
+	checkError(saved.playbackManager.AddAudioFilesToQueue("../../music/sample-12s.mp3"), t)
+	study.playbackManager.SetReplayGainMode(replaygain.ModeAlbum)
+	saved.savePlayerState()
+
+	// every partition comes back with its own queue
+	restored := newServer()
+	restored.restorePlayerState(false)
+	partitions := restored.partitions.all()
+	if len(partitions) != 2 || partitions[1].Name != "study" || partitions[1].playbackManager.GetStatus().QueueLength != 2 ||
+		restored.playbackManager.GetStatus().QueueLength != 1 {
+		t.Errorf("expected both partitions to be restored, got: %v", restored.partitions.Names())
+	}
+	if len(partitions) == 2 && (partitions[1].playbackManager.GetReplayGainSettings().Mode != replaygain.ModeAlbum ||
+		restored.playbackManager.GetReplayGainSettings().Mode == replaygain.ModeAlbum) {
+		t.Errorf("expected the replay gain mode of each partition to be restored")
+	}
+}
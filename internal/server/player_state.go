@@ -0,0 +1,178 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+	"github.com/arpitpandey992/go-mpd/internal/replaygain"
+	"github.com/arpitpandey992/go-mpd/internal/state"
+)
+
+// changes to these subsystems make the saved player state outdated
+var persistedSubsystems = []idle.Subsystem{idle.SubsystemPlaylist, idle.SubsystemPlayer, idle.SubsystemMixer, idle.SubsystemOptions}
+
+// restores the state of every partition saved by a previous run of the daemon, if there is any
+func (server *Server) restorePlayerState(resumePlayback bool) {
+	playerState, err := state.Load(server.stateFilePath)
+	if err != nil {
+		log.Printf("could not restore player state, error: %v", err)
+		return
+	}
+	if playerState == nil {
+		return
+	}
+	restorePlayback(server.playbackManager, playerState, resumePlayback)
+	for _, partitionState := range playerState.Partitions {
+		partition, err := server.partitions.create(partitionState.Name)
+		if err != nil {
+			log.Printf("could not restore partition %s, error: %v", partitionState.Name, err)
+			continue
+		}
+		restorePlayback(partition.playbackManager, &partitionState.PlayerState, resumePlayback)
+	}
+}
+
+func restorePlayback(playbackManager *playbackmanager.PlaybackManager, playerState *state.PlayerState, resumePlayback bool) {
+	snapshot := playbackmanager.PlaybackSnapshot{
+		QueuePosition: playerState.QueuePosition,
+		State:         playbackmanager.PlaybackState(playerState.State),
+		Elapsed:       playerState.Elapsed,
+		Options:       playbackManager.GetOptions(),
+		Volume:        playerState.Volume,
+		Muted:         playerState.Muted,
+	}
+	for _, entryState := range playerState.Queue {
+		snapshot.Queue = append(snapshot.Queue, getQueueEntry(entryState))
+	}
+	snapshot.Options.Repeat = playerState.Repeat
+	snapshot.Options.Random = playerState.Random
+	snapshot.Options.Single = playerState.Single
+	snapshot.Options.Consume = playerState.Consume
+	snapshot.Options.Crossfade = playerState.Crossfade
+	if playerState.ReplayGainMode != "" {
+		mode, err := replaygain.ParseMode(playerState.ReplayGainMode)
+		if err != nil {
+			log.Printf("could not restore the replay gain mode, error: %v", err)
+		} else {
+			playbackManager.SetReplayGainMode(mode)
+		}
+	}
+	err := playbackManager.RestoreSnapshot(snapshot, resumePlayback)
+	if err != nil {
+		log.Printf("could not restore playback, error: %v", err)
+	}
+}
+
+// saves the player state every interval while it changes, and once more when the returned function is called.
+// The elapsed time keeps changing during playback, so the state is saved on every interval while playing
+func (server *Server) persistPlayerState(interval time.Duration) (stop func()) {
+	listeners := map[*Partition]*idle.Listener{}
+	for _, partition := range server.partitions.all() {
+		listeners[partition] = partition.playbackManager.IdleNotifier().Listen()
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer func() {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+		}()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				server.savePlayerState()
+				return
+			case <-ticker.C:
+				if server.pollPlayerState(listeners) {
+					server.savePlayerState()
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// reports whether the state of a partition changed since the last poll or keeps changing while it plays. Partitions created
+// since the last poll are listened to from now on, and deleted ones are dropped, both of which change the state as well
+func (server *Server) pollPlayerState(listeners map[*Partition]*idle.Listener) bool {
+	changed := false
+	existing := map[*Partition]bool{}
+	for _, partition := range server.partitions.all() {
+		existing[partition] = true
+		listener, exists := listeners[partition]
+		if !exists {
+			listeners[partition] = partition.playbackManager.IdleNotifier().Listen()
+			changed = true
+			continue
+		}
+		if len(listener.Poll(persistedSubsystems)) > 0 || partition.playbackManager.GetStatus().State == playbackmanager.PlaybackStatePlaying {
+			changed = true
+		}
+	}
+	for partition, listener := range listeners {
+		if !existing[partition] {
+			listener.Close()
+			delete(listeners, partition)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (server *Server) savePlayerState() {
+	playerState := getPlayerState(server.playbackManager)
+	for _, partition := range server.partitions.all() {
+		if partition.Name != DEFAULT_PARTITION_NAME {
+			playerState.Partitions = append(playerState.Partitions, state.PartitionState{Name: partition.Name, PlayerState: *getPlayerState(partition.playbackManager)})
+		}
+	}
+	err := state.Save(server.stateFilePath, playerState)
+	if err != nil {
+		log.Printf("could not save player state, error: %v", err)
+	}
+}
+
+func getPlayerState(playbackManager *playbackmanager.PlaybackManager) *state.PlayerState {
+	snapshot := playbackManager.GetSnapshot()
+	playerState := &state.PlayerState{
+		QueuePosition:  snapshot.QueuePosition,
+		State:          string(snapshot.State),
+		Elapsed:        snapshot.Elapsed,
+		Repeat:         snapshot.Options.Repeat,
+		Random:         snapshot.Options.Random,
+		Single:         snapshot.Options.Single,
+		Consume:        snapshot.Options.Consume,
+		Crossfade:      snapshot.Options.Crossfade,
+		ReplayGainMode: string(playbackManager.GetReplayGainSettings().Mode),
+		Volume:         snapshot.Volume,
+		Muted:          snapshot.Muted,
+	}
+	for _, entry := range snapshot.Queue {
+		entryState := state.QueueEntryState{ID: entry.ID, URI: entry.URI, Kind: string(entry.Kind), Priority: entry.Priority}
+		if entry.Range != nil {
+			entryState.Start, entryState.End = entry.Range.Start, entry.Range.End
+		}
+		playerState.Queue = append(playerState.Queue, entryState)
+	}
+	return playerState
+}
+
+func getQueueEntry(entryState state.QueueEntryState) playbackmanager.QueueEntry {
+	if entryState.URI == "" {
+		entryState.URI, entryState.Kind = entryState.FilePath, string(playbackmanager.SourceKindFile)
+	}
+	entry := playbackmanager.QueueEntry{ID: entryState.ID, URI: entryState.URI, Kind: playbackmanager.SourceKind(entryState.Kind), Priority: entryState.Priority}
+	if entry.Kind == playbackmanager.SourceKindVirtual {
+		entry.Range = &playbackmanager.TrackRange{Start: entryState.Start, End: entryState.End}
+	}
+	return entry
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/arpitpandey992/go-mpd/internal/idle"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+	"github.com/arpitpandey992/go-mpd/internal/playlist"
+)
+
+type PlaylistRequestsHandler struct {
+	playbackManager *playbackmanager.PlaybackManager
+	playlists       *playlist.Store // nil when stored playlists are not configured
+}
+
+func getNewPlaylistRequestsHandler(playbackManager *playbackmanager.PlaybackManager, playlists *playlist.Store) *PlaylistRequestsHandler {
+	return &PlaylistRequestsHandler{
+		playbackManager: playbackManager,
+		playlists:       playlists,
+	}
+}
+
+func (prh *PlaylistRequestsHandler) HandlePlaylistRequest(commands []string) (string, error) {
+	if prh.playlists == nil {
+		return "", fmt.Errorf("stored playlists are not configured, set audio.playlist_directory in the config")
+	}
+	mainCommand := strings.ToLower(commands[0])
+	switch mainCommand {
+	case "list":
+		if err := expectArguments(commands, 0, 0); err != nil {
+			return "", err
+		}
+		return prh.listPlaylists()
+	case "show":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return prh.showPlaylist(commands[1])
+	case "save":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return prh.modified(prh.playlists.Save(commands[1], prh.playbackManager.GetQueue()), "saved the queue as %s", commands[1])
+	case "load":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		entries, err := prh.playlists.Load(commands[1])
+		if err != nil {
+			return "", err
+		}
+		if len(entries) == 0 {
+			return fmt.Sprintf("%s is empty", commands[1]), nil
+		}
+		if err := prh.playbackManager.AddEntriesToQueue(entries...); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("added %d tracks from %s", len(entries), commands[1]), nil
+	case "rename":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		return prh.modified(prh.playlists.Rename(commands[1], commands[2]), "renamed %s to %s", commands[1], commands[2])
+	case "delete":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return prh.modified(prh.playlists.Delete(commands[1]), "deleted %s", commands[1])
+	case "add":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		return prh.modified(prh.playlists.AddEntry(commands[1], commands[2]), "added %s to %s", path.Base(commands[2]), commands[1])
+	case "remove":
+		if err := expectArguments(commands, 2, 2); err != nil {
+			return "", err
+		}
+		position, err := strconv.Atoi(commands[2])
+		if err != nil {
+			return "", fmt.Errorf("invalid playlist position: %s", commands[2])
+		}
+		return prh.modified(prh.playlists.DeleteEntry(commands[1], position), "removed entry %d from %s", position, commands[1])
+	case "clear":
+		if err := expectArguments(commands, 1, 1); err != nil {
+			return "", err
+		}
+		return prh.modified(prh.playlists.Clear(commands[1]), "cleared %s", commands[1])
+	default:
+		return "", fmt.Errorf("unknown playlist command: %s", mainCommand)
+	}
+}
+
+func (prh *PlaylistRequestsHandler) listPlaylists() (string, error) {
+	playlists, err := prh.playlists.List()
+	if err != nil {
+		return "", err
+	}
+	if len(playlists) == 0 {
+		return "no stored playlists", nil
+	}
+	lines := []string{}
+	for _, info := range playlists {
+		lines = append(lines, fmt.Sprintf("%s (modified %s)", info.Name, info.LastModified.Format("2006-01-02 15:04")))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (prh *PlaylistRequestsHandler) showPlaylist(name string) (string, error) {
+	entries, err := prh.playlists.Load(name)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("%s is empty", name), nil
+	}
+	lines := []string{}
+	for position, entry := range entries {
+		line := fmt.Sprintf("%d: %s", position, path.Base(entry.URI))
+		if entry.Range != nil {
+			line += fmt.Sprintf(" (%s)", formatTrackRange(*entry.Range))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// notifies idle clients about a successful change to a stored playlist and returns the confirmation message
+func (prh *PlaylistRequestsHandler) modified(err error, format string, args ...any) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	prh.playbackManager.IdleNotifier().Notify(idle.SubsystemStoredPlaylist)
+	return fmt.Sprintf(format, args...), nil
+}
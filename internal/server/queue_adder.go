@@ -0,0 +1,172 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/arpitpandey992/go-mpd/internal/audiofiles"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+const (
+	DEFAULT_ADD_BATCH_SIZE    = 100 // files appended to the queue at a time by background additions
+	DEFAULT_FINISHED_ADD_JOBS = 10  // finished jobs kept around so clients can still see their result
+)
+
+// AddJob is an addition of files, directories or globs to the playback queue
+type AddJob struct {
+	ID        int
+	Arguments []string
+	Added     int
+	Rejected  []string // arguments which do not contain audio files and files which could not be added
+	Done      bool
+}
+
+// QueueAdder expands add arguments into audio files and appends them to the shared playback queue.
+// Additions of directories or globs can take a while, so they run in the background
+type QueueAdder struct {
+	playbackManager *playbackmanager.PlaybackManager
+	finder          *audiofiles.Finder
+
+	jobsLock  sync.Mutex
+	jobs      []*AddJob
+	lastJobId int
+}
+
+func newQueueAdder(playbackManager *playbackmanager.PlaybackManager, finder *audiofiles.Finder) *QueueAdder {
+	return &QueueAdder{
+		playbackManager: playbackManager,
+		finder:          finder,
+		jobs:            []*AddJob{},
+	}
+}
+
+// Add appends the arguments to the queue in their order. Plain files are added right away and the finished job is returned,
+// as soon as one argument is a directory or a glob the whole addition runs in the background and the returned job is still running
+func (adder *QueueAdder) Add(arguments []string) AddJob {
+	job := adder.startJob(arguments)
+	for _, argument := range arguments {
+		if !audiofiles.IsPlainFile(argument) {
+			go adder.run(job)
+			return adder.snapshot(job)
+		}
+	}
+	adder.run(job)
+	return adder.snapshot(job)
+}
+
+// Jobs returns copies of the running and the recently finished jobs, oldest first
+func (adder *QueueAdder) Jobs() []AddJob {
+	adder.jobsLock.Lock()
+	defer adder.jobsLock.Unlock()
+	jobs := []AddJob{}
+	for _, job := range adder.jobs {
+		jobs = append(jobs, adder.copyJob(job))
+	}
+	return jobs
+}
+
+func (adder *QueueAdder) startJob(arguments []string) *AddJob {
+	adder.jobsLock.Lock()
+	defer adder.jobsLock.Unlock()
+	adder.lastJobId++
+	job := &AddJob{ID: adder.lastJobId, Arguments: arguments, Rejected: []string{}}
+	adder.jobs = append(adder.jobs, job)
+	return job
+}
+
+func (adder *QueueAdder) run(job *AddJob) {
+	files, rejected := adder.finder.Expand(job.Arguments)
+	adder.updateJob(job, 0, rejected)
+	// the batches are undone together, like a single addition
+	step := adder.playbackManager.NewQueueStep()
+	for start := 0; start < len(files); start += DEFAULT_ADD_BATCH_SIZE {
+		batch := files[start:min(start+DEFAULT_ADD_BATCH_SIZE, len(files))]
+		rejected := []string{}
+		err := adder.playbackManager.AddEntriesToQueueInStep(step, newQueueEntries(batch)...)
+		var addError *playbackmanager.AddError
+		if errors.As(err, &addError) {
+			rejected = addError.Rejected
+		} else if err != nil {
+			log.Printf("could not add files to the queue, error: %v", err)
+			rejected = audiofiles.FilePaths(batch)
+		}
+		adder.updateJob(job, len(batch)-len(rejected), rejected)
+	}
+	adder.jobsLock.Lock()
+	defer adder.jobsLock.Unlock()
+	job.Done = true
+	adder.pruneFinishedJobs()
+	log.Printf("add job %d finished: added %d files, rejected %d", job.ID, job.Added, len(job.Rejected))
+}
+
+func (adder *QueueAdder) updateJob(job *AddJob, added int, rejected []string) {
+	adder.jobsLock.Lock()
+	defer adder.jobsLock.Unlock()
+	job.Added += added
+	job.Rejected = append(job.Rejected, rejected...)
+}
+
+// drops the oldest finished jobs beyond DEFAULT_FINISHED_ADD_JOBS, running jobs are always kept
+func (adder *QueueAdder) pruneFinishedJobs() {
+	finished := 0
+	for _, job := range adder.jobs {
+		if job.Done {
+			finished++
+		}
+	}
+	jobs := []*AddJob{}
+	for _, job := range adder.jobs {
+		if job.Done && finished > DEFAULT_FINISHED_ADD_JOBS {
+			finished--
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	adder.jobs = jobs
+}
+
+func (adder *QueueAdder) snapshot(job *AddJob) AddJob {
+	adder.jobsLock.Lock()
+	defer adder.jobsLock.Unlock()
+	return adder.copyJob(job)
+}
+
+// must be called with jobsLock held
+func (adder *QueueAdder) copyJob(job *AddJob) AddJob {
+	jobCopy := *job
+	jobCopy.Rejected = append([]string{}, job.Rejected...)
+	return jobCopy
+}
+
+// describes the job as a single line
+func describeAddJob(job AddJob) string {
+	if !job.Done {
+		return fmt.Sprintf("adding %s, added %d files so far", strings.Join(job.Arguments, ", "), job.Added)
+	}
+	description := fmt.Sprintf("added %d files to playback queue", job.Added)
+	if job.Added == 1 && len(job.Rejected) == 0 && len(job.Arguments) == 1 {
+		description = fmt.Sprintf("added %v to playback queue", path.Base(job.Arguments[0]))
+	}
+	if len(job.Rejected) > 0 {
+		description += ", rejected: " + strings.Join(job.Rejected, ", ")
+	}
+	return description
+}
+
+// creates queue entries for the files found, keeping the metadata which was looked up while finding them
+func newQueueEntries(files []audiofiles.File) []playbackmanager.QueueEntry {
+	entries := []playbackmanager.QueueEntry{}
+	for _, file := range files {
+		if file.Resolved {
+			entries = append(entries, playbackmanager.NewResolvedQueueEntry(file.Path, file.Metadata))
+		} else {
+			entries = append(entries, playbackmanager.NewQueueEntry(file.Path))
+		}
+	}
+	return entries
+}
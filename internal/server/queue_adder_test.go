@@ -0,0 +1,66 @@
+package server
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/audiofiles"
+	"github.com/arpitpandey992/go-mpd/internal/playbackmanager"
+)
+
+func TestQueueAdder(t *testing.T) {
+	playbackManager := playbackmanager.CreatePlaybackManager()
+	adder := newQueueAdder(playbackManager, audiofiles.NewFinder([]string{"mp3"}))
+
+	job := adder.Add([]string{"../../music/sample-3s.mp3", "../../music/missing.mp3"})
+	if !job.Done || job.Added != 1 || !reflect.DeepEqual(job.Rejected, []string{"../../music/missing.mp3"}) {
+		t.Fatalf("expected plain files to be added right away, got: %+v", job)
+	}
+
+	job = adder.Add([]string{"../../music", "../../music/sample-1*.mp3"})
+	if job.Done {
+		t.Fatalf("expected a directory to be added in the background, got: %+v", job)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.Done && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		jobs := adder.Jobs()
+		job = jobs[len(jobs)-1]
+	}
+	if !job.Done || job.Added != 6 || len(job.Rejected) != 0 {
+		t.Fatalf("expected the directory and glob to be added, got: %+v", job)
+	}
+	expected := []string{"sample-3s.mp3", "sample-3s.mp3", "sample-9s.mp3", "sample-12s.mp3", "sample-15s.mp3", "sample-12s.mp3", "sample-15s.mp3"}
+	names := []string{}
+	for _, entry := range playbackManager.GetQueue() {
+		names = append(names, filepath.Base(entry.URI))
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected: %q, got: %q", expected, names)
+	}
+}
+
+func TestMpdAddDirectory(t *testing.T) {
+	handler := getNewMpdRequestsHandler(server.partitions, nil, nil, io.Discard)
+	initialLength := server.playbackManager.GetStatus().QueueLength
+	if response := handler.HandleMpdLine("add ../../music"); response != "OK\n" {
+		t.Fatalf("expected the directory to be added, got: %q", response)
+	}
+	defer server.playbackManager.DeleteFromQueue(initialLength, initialLength+4)
+	if added := server.playbackManager.GetStatus().QueueLength - initialLength; added != 4 {
+		t.Errorf("expected 4 files to be added, got: %d", added)
+	}
+	if response := handler.HandleMpdLine("addid ../../music"); !strings.HasPrefix(response, "ACK [50@0] {addid}") {
+		t.Errorf("expected addid to reject directories, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("add ../../music/missing"); !strings.HasPrefix(response, "ACK [50@0] {add}") {
+		t.Errorf("expected a missing directory to be reported as such, got: %q", response)
+	}
+	if response := handler.HandleMpdLine("add " + t.TempDir()); !strings.HasPrefix(response, "ACK [2@0] {add} No audio files found") {
+		t.Errorf("expected a directory without audio files not to be reported as missing, got: %q", response)
+	}
+}
@@ -0,0 +1,86 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arpitpandey992/go-mpd/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// PlayerState is the part of the player which is kept across restarts of the daemon
+type PlayerState struct {
+	Queue         []QueueEntryState `yaml:"queue"`
+	QueuePosition int               `yaml:"queue_position"`
+	State         string            `yaml:"state"` // "playing", "paused" or "stopped"
+	Elapsed       time.Duration     `yaml:"elapsed"`
+
+	Repeat    bool          `yaml:"repeat"`
+	Random    bool          `yaml:"random"`
+	Single    bool          `yaml:"single"`
+	Consume   bool          `yaml:"consume"`
+	Crossfade time.Duration `yaml:"crossfade"`
+
+	ReplayGainMode string `yaml:"replay_gain_mode,omitempty"` // empty in older state files, the configured mode is kept then
+
+	Volume int  `yaml:"volume"`
+	Muted  bool `yaml:"muted"`
+
+	Partitions []PartitionState `yaml:"partitions,omitempty"` // the partitions other than the default one, whose state is the one above
+}
+
+// PartitionState is the state of a partition's player, kept along with its name
+type PartitionState struct {
+	Name        string `yaml:"name"`
+	PlayerState `yaml:",inline"`
+}
+
+type QueueEntryState struct {
+	ID    int           `yaml:"id"`
+	URI   string        `yaml:"uri"`
+	Kind  string        `yaml:"kind"`            // "file", "stream" or "virtual"
+	Start time.Duration `yaml:"start,omitempty"` // range of virtual tracks
+	End   time.Duration `yaml:"end,omitempty"`
+
+	Priority int `yaml:"priority,omitempty"`
+
+	FilePath string `yaml:"file_path,omitempty"` // written instead of the URI by older versions, which only queued files
+}
+
+// Load reads the state file, it returns nil without an error when the file does not exist yet
+func Load(filePath string) (*PlayerState, error) {
+	exists, err := utils.DoesFileExistsInFileSystem(filePath)
+	if err != nil || !exists {
+		return nil, err
+	}
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %w", err)
+	}
+	var playerState PlayerState
+	err = yaml.Unmarshal(fileContent, &playerState)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling state file: %w", err)
+	}
+	return &playerState, nil
+}
+
+// Save writes the state file. It is written to a temporary file first, so a crash never leaves a partially written state behind
+func Save(filePath string, playerState *PlayerState) error {
+	fileContent, err := yaml.Marshal(playerState)
+	if err != nil {
+		return fmt.Errorf("error marshalling state: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating state directory: %w", err)
+	}
+	temporaryFilePath := filePath + ".tmp"
+	err = os.WriteFile(temporaryFilePath, fileContent, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	return os.Rename(temporaryFilePath, filePath)
+}
@@ -0,0 +1,40 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "go-mpd", "state.yml")
+	playerState, err := Load(filePath)
+	if err != nil || playerState != nil {
+		t.Fatalf("expected no state before saving, got: %+v, %v", playerState, err)
+	}
+
+	expected := &PlayerState{
+		Queue:          []QueueEntryState{{ID: 3, URI: "music/a.flac", Kind: "file"}, {ID: 7, URI: "music/b.mp3", Kind: "virtual", Start: time.Second, End: time.Second * 5, Priority: 3}},
+		QueuePosition:  1,
+		State:          "paused",
+		Elapsed:        time.Second * 83,
+		Random:         true,
+		Crossfade:      time.Second * 4,
+		ReplayGainMode: "album",
+		Volume:         42,
+		Muted:          true,
+		Partitions:     []PartitionState{{Name: "study", PlayerState: PlayerState{Queue: []QueueEntryState{{ID: 1, URI: "music/c.mp3", Kind: "file"}}, State: "stopped", Volume: 80}}},
+	}
+	err = Save(filePath, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playerState, err = Load(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(playerState, expected) {
+		t.Errorf("expected: %+v, got: %+v", expected, playerState)
+	}
+}